@@ -1,10 +1,14 @@
 package main
 
 import (
+	"os"
+
 	"github.com/andykuszyk/markasten/internal/commands"
 )
 
 func main() {
 	rootCmd := commands.NewRootCmd()
-	rootCmd.Execute()
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
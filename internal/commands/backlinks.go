@@ -1,82 +1,661 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
-	"regexp"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	backlinksFindInputPath    *string
-	backlinksFindOutputPath   *string
-	backlinksFindDebugEnabled *bool
-	LinkRegexp                = regexp.MustCompile(`\[.*\]\(.*\)`)
+	backlinksFindInputPath       *string
+	backlinksFindRoot            *string
+	backlinksFindOutputPath      *string
+	backlinksFindFormat          *string
+	backlinksFindContextLength   *int
+	backlinksFindCheck           *bool
+	backlinksFindDebugEnabled    *bool
+	backlinksAppendInputPath     *string
+	backlinksAppendRoot          *string
+	backlinksAppendOutputPath    *string
+	backlinksAppendTitle         *string
+	backlinksAppendContextLength *int
+	backlinksAppendCheck         *bool
+	backlinksAppendDebugEnabled  *bool
 )
 
+const (
+	backlinksStartMarker = "<!-- markasten:backlinks:start -->"
+	backlinksEndMarker   = "<!-- markasten:backlinks:end -->"
+)
+
+// backlink is a link found in a source file, which refers to a target file.
+// If the link refers to a fragment of the target, section is the heading
+// the fragment matches, or is empty if no heading matches it.
+type backlink struct {
+	source   indexedFile
+	target   string
+	link     Link
+	context  snippet
+	fragment string
+	section  heading
+}
+
+// backlinkEntry is a backlink in the output of backlinks find.
+type backlinkEntry struct {
+	Source  string `json:"source" yaml:"source"`
+	Target  string `json:"target" yaml:"target"`
+	Text    string `json:"text" yaml:"text"`
+	Line    int    `json:"line" yaml:"line"`
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+	// Section is the heading in the target that the link refers to, or the
+	// fragment of the link if it does not match a heading.
+	Section        string `json:"section,omitempty" yaml:"section,omitempty"`
+	MissingSection bool   `json:"missingSection,omitempty" yaml:"missingSection,omitempty"`
+	sectionLine    int
+	column         int
+}
+
 func newBacklinksCommand() *cobra.Command {
 	backlinkCommand := &cobra.Command{
 		Use: "backlinks",
 	}
 	findCommand := &cobra.Command{
-		Use:  "find",
-		RunE: backlinkFindRunFn,
+		Use:          "find",
+		RunE:         backlinkFindRunFn,
+		SilenceUsage: true,
 	}
 	backlinksFindDebugEnabled = findCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
 	backlinksFindInputPath = findCommand.Flags().StringP("input", "i", "", "The location of the input files")
-	backlinksFindOutputPath = findCommand.Flags().StringP("output", "o", "", "The location of the output file")
+	backlinksFindOutputPath = findCommand.Flags().StringP("output", "o", "", "The location of an optional file to write the backlinks to, instead of stdout")
+	backlinksFindRoot = findCommand.Flags().String("root", "", rootFlagUsage)
+	backlinksFindFormat = findCommand.Flags().String("format", "yaml", "The format of the backlinks, either yaml or json")
+	backlinksFindContextLength = findCommand.Flags().Int("context-length", 120, "The maximum length of the snippet of text surrounding each link, or 0 to omit it")
+	backlinksFindCheck = findCommand.Flags().Bool("check", false, checkFlagUsage)
 	backlinkCommand.AddCommand(findCommand)
+	appendCommand := &cobra.Command{
+		Use:          "append",
+		RunE:         backlinkAppendRunFn,
+		SilenceUsage: true,
+	}
+	backlinksAppendDebugEnabled = appendCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	backlinksAppendInputPath = appendCommand.Flags().StringP("input", "i", "", "The location of the files to search for backlinks")
+	backlinksAppendOutputPath = appendCommand.Flags().StringP("output", "o", "", "The location of the files to append backlinks to")
+	backlinksAppendRoot = appendCommand.Flags().String("root", "", rootFlagUsage)
+	backlinksAppendTitle = appendCommand.Flags().StringP("title", "t", "Backlinks", "The title of the appended backlinks section")
+	backlinksAppendContextLength = appendCommand.Flags().Int("context-length", 120, "The maximum length of the snippet of text surrounding each link, or 0 to omit it")
+	backlinksAppendCheck = appendCommand.Flags().Bool("check", false, "If set, files will not be changed, and instead the command will fail with a diff if any of their backlinks are out of date")
+	backlinkCommand.AddCommand(appendCommand)
 	debugEnabled = backlinksFindDebugEnabled
 	return backlinkCommand
 }
 
 func backlinkFindRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = backlinksFindDebugEnabled
 	debug("backlink find called with -i %s and -o %s\n", *backlinksFindInputPath, *backlinksFindOutputPath)
+	var render func([]backlinkEntry) string
+	switch *backlinksFindFormat {
+	case "yaml":
+		render = renderBacklinksYAML
+	case "json":
+		render = renderBacklinksJSON
+	default:
+		return fmt.Errorf("unsupported format %q, expected yaml or json", *backlinksFindFormat)
+	}
 	inputDirEntires, err := newFullDirEntryList(*backlinksFindInputPath)
 	if err != nil {
 		panic(err)
 	}
 
-	backlinksByFile := make(map[string][]string)
 	searchResults, err := searchForMarkdownFiles(inputDirEntires, *backlinksFindInputPath)
 	if err != nil {
 		panic(err)
 	}
-	for _, dirEntry := range searchResults {
-		fileBytes, err := os.ReadFile(dirEntry.Name())
-		if err != nil {
-			panic(err)
+	backlinksByTarget, missing := findBacklinks(searchResults, *backlinksFindInputPath, linkRoot(*backlinksFindRoot, *backlinksFindInputPath), nil)
+	reportMissingLinks(cmd, missing)
+	reportMissingSections(cmd, backlinksByTarget)
+
+	// Paths are relative to the output file, so that they can be followed
+	// from it, or to the input files if the output is written to stdout.
+	relativeToPath := *backlinksFindOutputPath
+	if relativeToPath == "" {
+		relativeToPath = filepath.Join(*backlinksFindInputPath, "backlinks")
+	}
+	entries := []backlinkEntry{}
+	for target, backlinks := range backlinksByTarget {
+		for _, b := range backlinks {
+			entry := backlinkEntry{
+				Source:      filepath.ToSlash(relativeTo(b.source.fileName, relativeToPath)),
+				Target:      filepath.ToSlash(relativeTo(target, relativeToPath)),
+				Text:        b.link.Text,
+				Line:        b.link.Line,
+				Context:     contextSnippet(b, *backlinksFindContextLength),
+				Section:     b.section.text,
+				sectionLine: b.section.line,
+				column:      b.link.Column,
+			}
+			if b.missingSection() {
+				entry.Section = b.fragment
+				entry.MissingSection = true
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Target != entries[j].Target {
+			return entries[i].Target < entries[j].Target
+		}
+		// Within each target, links to the whole note come first, then
+		// links to each of its sections in order, then links to sections
+		// which do not exist.
+		if entries[i].MissingSection != entries[j].MissingSection {
+			return !entries[i].MissingSection
+		}
+		if entries[i].sectionLine != entries[j].sectionLine {
+			return entries[i].sectionLine < entries[j].sectionLine
+		}
+		if entries[i].Section != entries[j].Section {
+			return entries[i].Section < entries[j].Section
 		}
-		backlinksByFile[dirEntry.Name()] = scrapeBacklinks(fileBytes)
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].column < entries[j].column
+	})
+
+	return writeOutput(cmd, *backlinksFindOutputPath, render(entries), *backlinksFindCheck)
+}
+
+func backlinkAppendRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = backlinksAppendDebugEnabled
+	debug("backlink append called with -i %s and -o %s\n", *backlinksAppendInputPath, *backlinksAppendOutputPath)
+	inputDirEntries, err := newFullDirEntryList(*backlinksAppendInputPath)
+	if err != nil {
+		panic(err)
+	}
+	sources, err := searchForMarkdownFiles(inputDirEntries, *backlinksAppendInputPath)
+	if err != nil {
+		panic(err)
 	}
+	// Any previously appended section is ignored, otherwise the links
+	// it contains would be found as backlinks on the next run.
+	backlinksByTarget, missing := findBacklinks(sources, *backlinksAppendInputPath, linkRoot(*backlinksAppendRoot, *backlinksAppendInputPath), func(fileBytes []byte) []byte {
+		return []byte(stripMarkedSection(string(fileBytes), backlinksStartMarker, backlinksEndMarker))
+	})
+	reportMissingLinks(cmd, missing)
+	reportMissingSections(cmd, backlinksByTarget)
 
-	outputFile, err := os.Create(*backlinksFindOutputPath)
+	outputDirEntries, err := newFullDirEntryList(*backlinksAppendOutputPath)
 	if err != nil {
 		panic(err)
 	}
-	defer outputFile.Close()
-	for fileName, backlinks := range backlinksByFile {
-		writeOrPanic(outputFile, fmt.Sprintf("%s:\n", relativeTo(fileName, *backlinksFindOutputPath)))
-		for _, backlink := range backlinks {
-			writeOrPanic(outputFile, fmt.Sprintf("  - %s\n", relativeTo(backlink, *backlinksFindOutputPath)))
+	targets, err := searchForMarkdownFiles(outputDirEntries, *backlinksAppendOutputPath)
+	if err != nil {
+		panic(err)
+	}
+	stale := 0
+	for _, dirEntry := range targets {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		contents := string(fileBytes)
+		backlinks := backlinksByTarget[absPath(dirEntry.Name())]
+		var updated string
+		if len(backlinks) == 0 {
+			updated = stripMarkedSection(contents, backlinksStartMarker, backlinksEndMarker)
+		} else {
+			updated, err = replaceMarkedSection(
+				contents,
+				backlinksStartMarker,
+				backlinksEndMarker,
+				renderBacklinksSection(backlinks, dirEntry.Name()),
+			)
+			if err != nil {
+				return fmt.Errorf("%s: %w", dirEntry.Name(), err)
+			}
 		}
+		if updated == contents {
+			continue
+		}
+		if *backlinksAppendCheck {
+			if err := checkOutput(cmd, dirEntry.Name(), updated); err != nil {
+				stale++
+			}
+			continue
+		}
+		debug("appending %d backlinks to %s", len(backlinks), dirEntry.Name())
+		if err := os.WriteFile(dirEntry.Name(), []byte(updated), 0644); err != nil {
+			panic(err)
+		}
+	}
+	if stale > 0 {
+		return fmt.Errorf("found %d files with out of date backlinks", stale)
 	}
 	return nil
 }
 
-func scrapeBacklinks(fileBytes []byte) []string {
-	lines := strings.Split(string(fileBytes), "\n")
-	var backlinks []string
-	for _, line := range lines {
-		matches := LinkRegexp.FindAllString(line, -1)
-		if matches == nil {
+// findBacklinks scrapes the links from each of the given Markdown files, and
+// returns them grouped by the absolute path of the file they link to. Links to files
+// which do not exist are returned separately. Wiki links are resolved against
+// the files in inputPath, and root-absolute links against root. If preprocess
+// is not nil, it is applied to the contents of each file before its links are
+// scraped.
+func findBacklinks(
+	dirEntries []fullDirEntry,
+	inputPath string,
+	root string,
+	preprocess func([]byte) []byte,
+) (map[string][]backlink, []backlink) {
+	contentsByFile := make(map[string][]byte)
+	for _, dirEntry := range dirEntries {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		if preprocess != nil {
+			fileBytes = preprocess(fileBytes)
+		}
+		contentsByFile[dirEntry.Name()] = fileBytes
+	}
+	pages := newWikiPages(dirEntries, inputPath, contentsByFile)
+
+	anchorsByTarget := make(map[string]map[string]heading)
+	backlinksByTarget := make(map[string][]backlink)
+	var missing []backlink
+	for _, dirEntry := range dirEntries {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		for _, b := range scrapeBacklinks(dirEntry.Name(), root, contentsByFile[dirEntry.Name()], pages) {
+			if b.target == "" {
+				debug("%s links to wiki page %s, which does not exist", b.source.fileName, b.link.Destination)
+				missing = append(missing, b)
+				continue
+			}
+			info, err := os.Stat(b.target)
+			if err != nil {
+				debug("%s links to %s, which does not exist", b.source.fileName, b.target)
+				missing = append(missing, b)
+				continue
+			}
+			target := absPath(b.target)
+			if info.IsDir() || target == absPath(b.source.fileName) {
+				continue
+			}
+			if b.fragment != "" && isMarkdownFile(target) {
+				anchors, ok := anchorsByTarget[target]
+				if !ok {
+					fileBytes, err := os.ReadFile(target)
+					if err != nil {
+						panic(err)
+					}
+					if preprocess != nil {
+						fileBytes = preprocess(fileBytes)
+					}
+					anchors = extractAnchors(string(fileBytes))
+					anchorsByTarget[target] = anchors
+				}
+				b.section = anchors[b.fragment]
+			}
+			backlinksByTarget[target] = append(backlinksByTarget[target], b)
+		}
+	}
+	return backlinksByTarget, missing
+}
+
+func reportMissingLinks(cmd *cobra.Command, missing []backlink) {
+	for _, b := range missing {
+		cmd.PrintErrf("%s:%d: link to %s, which does not exist\n", b.source.fileName, b.link.Line, b.link.Destination)
+	}
+}
+
+// reportMissingSections reports the backlinks which refer to a section of
+// their target which does not exist.
+func reportMissingSections(cmd *cobra.Command, backlinksByTarget map[string][]backlink) {
+	var messages []string
+	for target, backlinks := range backlinksByTarget {
+		for _, b := range backlinks {
+			if !b.missingSection() {
+				continue
+			}
+			messages = append(messages, fmt.Sprintf(
+				"%s:%d: link to %s, which does not match a heading in %s\n",
+				b.source.fileName,
+				b.link.Line,
+				b.link.Destination,
+				filepath.Base(target),
+			))
+		}
+	}
+	sort.Strings(messages)
+	for _, message := range messages {
+		cmd.PrintErr(message)
+	}
+}
+
+// missingSection returns whether the backlink refers to a fragment of its
+// target which does not match a heading.
+func (b backlink) missingSection() bool {
+	return b.fragment != "" && b.section.slug == ""
+}
+
+// uniqueSources returns the files the given backlinks originate from, with
+// files which contain more than one of the backlinks only included once.
+func uniqueSources(backlinks []backlink) []indexedFile {
+	var sources []indexedFile
+	for _, b := range backlinks {
+		if containsFile(sources, b.source.fileName) {
+			continue
+		}
+		sources = append(sources, b.source)
+	}
+	return sources
+}
+
+func scrapeBacklinks(fileName string, root string, fileBytes []byte, pages wikiPages) []backlink {
+	_, title := scrapeTagsAndTitle(fileName, fileBytes)
+	source := indexedFile{
+		fileName: fileName,
+		title:    title,
+	}
+	lines := splitMarkdownLines(string(fileBytes))
+	links := ExtractLinks(string(fileBytes))
+	var backlinks []backlink
+	for _, link := range links {
+		if link.Image {
 			continue
 		}
-		for _, match := range matches {
-			backlinks = append(backlinks, match)
+		var target string
+		if link.Kind == WikiLink {
+			link = pages.orient(link)
+			target, _ = pages.resolveLink(link)
+		} else {
+			var ok bool
+			target, ok = resolveLink(root, fileName, link.Destination)
+			if !ok {
+				continue
+			}
 		}
+		backlinks = append(backlinks, backlink{
+			source:   source,
+			target:   target,
+			link:     link,
+			context:  linkSnippet(lines, links, link),
+			fragment: linkFragment(link),
+		})
 	}
 	return backlinks
 }
+
+func renderBacklinksYAML(entries []backlinkEntry) string {
+	var builder strings.Builder
+	encoder := yaml.NewEncoder(&builder)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(entries); err != nil {
+		panic(err)
+	}
+	if err := encoder.Close(); err != nil {
+		panic(err)
+	}
+	return builder.String()
+}
+
+func renderBacklinksJSON(entries []backlinkEntry) string {
+	entriesBytes, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(entriesBytes) + "\n"
+}
+
+// contextSnippet returns the snippet of text surrounding a backlink,
+// trimmed to the given length. If the length is not positive, the snippet is
+// omitted.
+func contextSnippet(b backlink, length int) string {
+	if length <= 0 {
+		return ""
+	}
+	return b.context.trim(length)
+}
+
+// renderBacklinksSection renders the backlinks to a target, grouped by the
+// section of the target they refer to. Backlinks to the whole note are
+// listed first, followed by those to each of its sections in order, and
+// those to sections which do not exist.
+func renderBacklinksSection(backlinks []backlink, target string) string {
+	lines := []string{
+		backlinksStartMarker,
+		fmt.Sprintf("## %s", *backlinksAppendTitle),
+	}
+	var noteBacklinks []backlink
+	var sections []heading
+	var missingFragments []string
+	for _, b := range backlinks {
+		switch {
+		case b.fragment == "":
+			noteBacklinks = append(noteBacklinks, b)
+		case b.missingSection():
+			if !containsString(missingFragments, b.fragment) {
+				missingFragments = append(missingFragments, b.fragment)
+			}
+		default:
+			if !containsHeading(sections, b.section.slug) {
+				sections = append(sections, b.section)
+			}
+		}
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].line < sections[j].line
+	})
+	sort.Strings(missingFragments)
+
+	lines = append(lines, renderBacklinkSources(noteBacklinks, target)...)
+	for _, section := range sections {
+		var sectionBacklinks []backlink
+		for _, b := range backlinks {
+			if !b.missingSection() && b.fragment != "" && b.section.slug == section.slug {
+				sectionBacklinks = append(sectionBacklinks, b)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("### %s", section.text))
+		lines = append(lines, renderBacklinkSources(sectionBacklinks, target)...)
+	}
+	for _, fragment := range missingFragments {
+		var sectionBacklinks []backlink
+		for _, b := range backlinks {
+			if b.missingSection() && b.fragment == fragment {
+				sectionBacklinks = append(sectionBacklinks, b)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("### #%s (heading not found)", fragment))
+		lines = append(lines, renderBacklinkSources(sectionBacklinks, target)...)
+	}
+	lines = append(lines, backlinksEndMarker)
+	return strings.Join(lines, "\n")
+}
+
+// renderBacklinkSources renders a list item for each file the given
+// backlinks originate from, with the snippet of each backlink listed under
+// it, as in Obsidian's backlinks pane.
+func renderBacklinkSources(backlinks []backlink, target string) []string {
+	var lines []string
+	for _, source := range uniqueSources(backlinks) {
+		relativePath := relativeTo(source.fileName, target)
+		lines = append(lines, fmt.Sprintf("- [%s](%s)", source.title, relativePath))
+		var snippets []string
+		for _, b := range backlinks {
+			context := contextSnippet(b, *backlinksAppendContextLength)
+			if b.source.fileName != source.fileName || context == "" || containsString(snippets, context) {
+				continue
+			}
+			snippets = append(snippets, context)
+			lines = append(lines, fmt.Sprintf("  - %s", context))
+		}
+	}
+	return lines
+}
+
+// resolveLink returns the path of the file a link destination refers to.
+// Relative destinations are resolved against the directory of the file
+// containing the link, and absolute destinations against the root of the
+// input files. Any query string or fragment is discarded. If the destination
+// is an external URL, or only refers to a fragment in the same file, false is
+// returned.
+func resolveLink(root string, source string, destination string) (string, bool) {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	if path.IsAbs(u.Path) {
+		return filepath.Join(root, filepath.FromSlash(u.Path)), true
+	}
+	return filepath.Join(filepath.Dir(source), filepath.FromSlash(u.Path)), true
+}
+
+// linkFragment returns the fragment of a link destination, as the slug of
+// the heading it refers to. Wiki links refer to headings by their text,
+// rather than their slug.
+func linkFragment(link Link) string {
+	if link.Kind == WikiLink {
+		if _, heading, ok := strings.Cut(link.Destination, "#"); ok {
+			return headingSlug(heading)
+		}
+		return ""
+	}
+	u, err := url.Parse(link.Destination)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Fragment)
+}
+
+// wikiPageName returns the page name a wiki link destination of the form
+// Page Name#Heading refers to.
+func wikiPageName(destination string) string {
+	page, _, _ := strings.Cut(destination, "#")
+	return strings.TrimSpace(page)
+}
+
+// wikiPages indexes files by the names that wiki links can refer to them by.
+type wikiPages struct {
+	byName  map[string]string
+	byTitle map[string]string
+}
+
+func newWikiPages(dirEntries []fullDirEntry, root string, contentsByFile map[string][]byte) wikiPages {
+	pages := wikiPages{
+		byName:  make(map[string]string),
+		byTitle: make(map[string]string),
+	}
+	for _, dirEntry := range dirEntries {
+		fileName := dirEntry.Name()
+		names := []string{filepath.Base(fileName)}
+		if relative, err := filepath.Rel(root, fileName); err == nil {
+			names = append(names, filepath.ToSlash(relative))
+		}
+		for _, name := range names {
+			key := wikiPageKey(makeWikiLink(name))
+			if _, ok := pages.byName[key]; !ok {
+				pages.byName[key] = fileName
+			}
+		}
+		_, title := scrapeTagsAndTitle(fileName, contentsByFile[fileName])
+		if _, ok := pages.byTitle[title]; title != "" && !ok {
+			pages.byTitle[title] = fileName
+		}
+	}
+	return pages
+}
+
+// resolve returns the file a wiki page name refers to, by first matching
+// the page name against file names without their extension, and then
+// against the titles of files.
+func (p wikiPages) resolve(page string) (string, bool) {
+	if fileName, ok := p.byName[wikiPageKey(page)]; ok {
+		return fileName, true
+	}
+	if fileName, ok := p.byTitle[page]; ok {
+		return fileName, true
+	}
+	return "", false
+}
+
+// resolveLink returns the file a wiki link refers to. As well as links of
+// the form [[Page Name|label]], links written in the GitHub wiki style of
+// [[label|Page Name]] are supported.
+func (p wikiPages) resolveLink(link Link) (string, bool) {
+	return p.resolve(wikiPageName(p.orient(link).Destination))
+}
+
+// orient returns a wiki link with its destination and text swapped if it is
+// written in the GitHub wiki style of [[label|Page Name]], which is the case
+// if its label refers to a page and its page name does not, or if both refer
+// to the same page but only the label refers to a heading.
+func (p wikiPages) orient(link Link) Link {
+	if link.Kind != WikiLink || link.textStart == link.textEnd {
+		return link
+	}
+	labelTarget, ok := p.resolve(wikiPageName(link.Text))
+	if !ok {
+		return link
+	}
+	if target, ok := p.resolve(wikiPageName(link.Destination)); ok {
+		if target != labelTarget || strings.Contains(link.Destination, "#") || !strings.Contains(link.Text, "#") {
+			return link
+		}
+	}
+	link.Text, link.Destination = link.Destination, link.Text
+	link.textStart, link.destinationStart = link.destinationStart, link.textStart
+	link.textEnd, link.destinationEnd = link.destinationEnd, link.textEnd
+	return link
+}
+
+// wikiPageKey normalises a page name in the same way as GitHub wikis, where
+// [[Page Name]] refers to the file Page-Name.md.
+func wikiPageKey(page string) string {
+	return strings.ToLower(strings.ReplaceAll(page, " ", "-"))
+}
+
+func containsFile(files []indexedFile, fileName string) bool {
+	for _, f := range files {
+		if f.fileName == fileName {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHeading(headings []heading, slug string) bool {
+	for _, h := range headings {
+		if h.slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
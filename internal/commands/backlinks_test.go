@@ -1,8 +1,10 @@
 package commands_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/andykuszyk/markasten/internal/commands"
@@ -14,6 +16,11 @@ func TestBacklinksFind(t *testing.T) {
 	for _, tc := range []testCase{
 		basicBacklinksFind(),
 		basicBacklinksFindWithMultipleFiles(),
+		backlinksFindResolvesRelativeLinks(),
+		backlinksFindWithWikiLinks(),
+		backlinksFindIgnoresLinksInCode(),
+		backlinksFindAsJSON(),
+		backlinksFindIgnoresNonMarkdownFiles(),
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
@@ -50,29 +57,103 @@ func TestBacklinksFind(t *testing.T) {
 	}
 }
 
-type linkRegexpTestCase struct {
-	input   string
-	matches []string
+func TestBacklinksFindWithRelativePaths(t *testing.T) {
+	tc := basicBacklinksFind()
+	inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Dir(inputDir)))
+	defer os.Chdir(workingDir)
+	relativeInputDir := filepath.Base(inputDir)
+
+	t.Run("output file", func(t *testing.T) {
+		outputFilePath := filepath.Join(relativeInputDir, tc.outputFiles[0].name)
+		rootCmd := commands.NewRootCmd()
+		rootCmd.SetArgs([]string{"backlinks", "find", "-i", relativeInputDir, "-o", outputFilePath})
+		require.NoError(t, rootCmd.Execute())
+
+		actualOutputBytes, err := os.ReadFile(outputFilePath)
+		require.NoError(t, err)
+		require.Equal(t, strings.Join(tc.outputFiles[0].contents, "\n"), string(actualOutputBytes))
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		var out bytes.Buffer
+		rootCmd := commands.NewRootCmd()
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs([]string{"backlinks", "find", "-i", relativeInputDir})
+		require.NoError(t, rootCmd.Execute())
+		require.Equal(t, strings.Join(tc.outputFiles[0].contents, "\n"), out.String())
+	})
 }
 
-func TestLinkRegexp(t *testing.T) {
-	for _, tc := range []linkRegexpTestCase{
-		{
-			input:   "foo bar spam",
-			matches: []string{},
-		},
-		{
-			input:   "foo [bar](spam)",
-			matches: []string{"[bar](spam)"},
-		},
+func TestBacklinksAppend(t *testing.T) {
+	for _, tc := range []testCase{
+		basicBacklinksAppend(),
+		backlinksAppendReplacesExistingSection(),
+		backlinksAppendWithFilesInSubDirectories(),
+		backlinksAppendWithContextSnippets(),
+		backlinksAppendGroupedBySection(),
 	} {
-		t.Run(tc.input, func(t *testing.T) {
-			matches := commands.LinkRegexp.FindAllString(tc.input, -1)
-			require.ElementsMatch(t, tc.matches, matches)
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+
+			// Running the command twice should produce the same output as
+			// running it once.
+			for i := 0; i < 2; i++ {
+				rootCmd := commands.NewRootCmd()
+				args := []string{
+					"backlinks",
+					"append",
+					"--debug",
+					"-i",
+					inputDir,
+					"-o",
+					inputDir,
+				}
+				args = append(args, tc.additionalArgs...)
+				rootCmd.SetArgs(args)
+				require.NoError(t, rootCmd.Execute())
+			}
+
+			for _, outputFile := range tc.outputFiles {
+				actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, outputFile.name))
+				require.NoError(t, err)
+				require.Equal(t, strings.Join(outputFile.contents, "\n"), string(actualOutputBytes))
+			}
 		})
 	}
 }
 
+func TestBacklinksAppendCheck(t *testing.T) {
+	tc := basicBacklinksAppend()
+	inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+	run := func(additionalArgs ...string) error {
+		rootCmd := commands.NewRootCmd()
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		rootCmd.SetArgs(append([]string{
+			"backlinks",
+			"append",
+			"-i",
+			inputDir,
+			"-o",
+			inputDir,
+		}, additionalArgs...))
+		return rootCmd.Execute()
+	}
+
+	require.EqualError(t, run("--check"), "found 1 files with out of date backlinks")
+	for _, inputFile := range tc.inputFiles {
+		actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, inputFile.name))
+		require.NoError(t, err)
+		require.Equal(t, strings.Join(inputFile.contents, "\n"), string(actualOutputBytes))
+	}
+
+	require.NoError(t, run())
+	require.NoError(t, run("--check"))
+}
+
 func basicBacklinksFind() testCase {
 	return testCase{
 		name: "basic backlinks find",
@@ -96,17 +177,38 @@ func basicBacklinksFind() testCase {
 			{
 				name: "backlinks.yml",
 				contents: []string{
-					"foo.md:",
-					"  - bar.md",
+					"- source: foo.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 2",
+					"  context: Foo mentions bar",
+					"",
 				},
 			},
 		},
 	}
 }
 
+func backlinksFindIgnoresNonMarkdownFiles() testCase {
+	tc := basicBacklinksFind()
+	tc.name = "backlinks find ignores non-markdown files"
+	tc.inputFiles = append(tc.inputFiles,
+		file{
+			name:     "notes.txt",
+			contents: []string{"Notes mention [bar](bar.md) and [a missing file](missing.md)."},
+		},
+		file{
+			name:     "config.yml",
+			contents: []string{"link: '[bar](bar.md)'"},
+		},
+	)
+	return tc
+}
+
 func basicBacklinksFindWithMultipleFiles() testCase {
 	return testCase{
-		name: "backlinks find with multiple files",
+		name:           "backlinks find with multiple files",
+		additionalArgs: []string{"--context-length", "0"},
 		inputFiles: []file{
 			{
 				name: "foo.md",
@@ -134,10 +236,529 @@ func basicBacklinksFindWithMultipleFiles() testCase {
 			{
 				name: "backlinks.yml",
 				contents: []string{
-					"foo.md:",
-					"  - bar.md",
-					"spam.md:",
-					"  - bar.md",
+					"- source: foo.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 2",
+					"- source: spam.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 2",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksFindResolvesRelativeLinks() testCase {
+	return testCase{
+		name:           "backlinks find resolves relative links",
+		additionalArgs: []string{"--context-length", "0"},
+		inputFiles: []file{
+			{
+				name: "team/foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](../bar.md#some-heading).",
+					"It also mentions [eggs](../eggs.md), which does not exist.",
+				},
+			},
+			{
+				name: "team/spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [bar](./../bar.md?plain=1).",
+					"It also mentions [bar](/bar.md) by its absolute path.",
+					"And [google](https://google.com).",
+					"And [itself](#spam).",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo and spam.",
+					"## Some heading",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "backlinks.yml",
+				contents: []string{
+					"- source: team/spam.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 2",
+					"- source: team/spam.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 3",
+					"- source: team/foo.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 2",
+					"  section: Some heading",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksFindWithWikiLinks() testCase {
+	return testCase{
+		name:           "backlinks find with wiki links",
+		additionalArgs: []string{"--context-length", "0"},
+		inputFiles: []file{
+			{
+				name: "a.md",
+				contents: []string{
+					"# A",
+					"A mentions [[page]] by its file name.",
+				},
+			},
+			{
+				name: "b.md",
+				contents: []string{
+					"# B",
+					"B mentions [[Page Title|the page]] by its title.",
+				},
+			},
+			{
+				name: "c.md",
+				contents: []string{
+					"# C",
+					"C mentions a heading in [[team/page#Some Heading]].",
+				},
+			},
+			{
+				name: "d.md",
+				contents: []string{
+					"# D",
+					"D mentions [[the page|Page Title]] in the GitHub wiki style.",
+				},
+			},
+			{
+				name: "e.md",
+				contents: []string{
+					"# E",
+					"E mentions [[Missing Page]], which does not exist.",
+				},
+			},
+			{
+				name: "team/page.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- page",
+					"---",
+					"# Page Title",
+					"Page is mentioned by a, b, c and d.",
+					"",
+					"## Some Heading",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "backlinks.yml",
+				contents: []string{
+					"- source: a.md",
+					"  target: team/page.md",
+					"  text: page",
+					"  line: 2",
+					"- source: b.md",
+					"  target: team/page.md",
+					"  text: the page",
+					"  line: 2",
+					"- source: d.md",
+					"  target: team/page.md",
+					"  text: the page",
+					"  line: 2",
+					"- source: c.md",
+					"  target: team/page.md",
+					"  text: team/page#Some Heading",
+					"  line: 2",
+					"  section: Some Heading",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksFindIgnoresLinksInCode() testCase {
+	return testCase{
+		name:           "backlinks find ignores links in code",
+		additionalArgs: []string{"--context-length", "0"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](bar.md) and [bar again](./bar.md) on one line.",
+					"",
+					"```markdown",
+					"[spam](spam.md)",
+					"```",
+					"And `[spam](spam.md)` in a code span.",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "backlinks.yml",
+				contents: []string{
+					"- source: foo.md",
+					"  target: bar.md",
+					"  text: bar",
+					"  line: 2",
+					"- source: foo.md",
+					"  target: bar.md",
+					"  text: bar again",
+					"  line: 2",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksFindAsJSON() testCase {
+	return testCase{
+		name:           "backlinks find as json",
+		additionalArgs: []string{"--format", "json", "--context-length", "0"},
+		inputFiles: []file{
+			{
+				name: "spam: eggs.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [foo](foo.md).",
+					"And [bar](bar.md).",
+				},
+			},
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](bar.md) and [[spam: eggs]].",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "backlinks.json",
+				contents: []string{
+					"[",
+					"  {",
+					`    "source": "foo.md",`,
+					`    "target": "bar.md",`,
+					`    "text": "bar",`,
+					`    "line": 2`,
+					"  },",
+					"  {",
+					`    "source": "spam: eggs.md",`,
+					`    "target": "bar.md",`,
+					`    "text": "bar",`,
+					`    "line": 3`,
+					"  },",
+					"  {",
+					`    "source": "spam: eggs.md",`,
+					`    "target": "foo.md",`,
+					`    "text": "foo",`,
+					`    "line": 2`,
+					"  },",
+					"  {",
+					`    "source": "foo.md",`,
+					`    "target": "spam: eggs.md",`,
+					`    "text": "spam: eggs",`,
+					`    "line": 2`,
+					"  }",
+					"]",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func basicBacklinksAppend() testCase {
+	return testCase{
+		name: "basic backlinks append",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](./bar.md)",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo.",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](./bar.md)",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo.",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Backlinks",
+					"- [Foo](foo.md)",
+					"  - Foo mentions bar",
+					"<!-- markasten:backlinks:end -->",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksAppendReplacesExistingSection() testCase {
+	return testCase{
+		name:           "backlinks append replaces an existing section",
+		additionalArgs: []string{"-t", "Referenced by", "--context-length", "0"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- foo",
+					"---",
+					"# Foo",
+					"Foo mentions [bar](./bar.md)",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo.",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Backlinks",
+					"- [Spam](spam.md)",
+					"<!-- markasten:backlinks:end -->",
+					"",
+					"Some text after the backlinks.",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam no longer mentions anything.",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Backlinks",
+					"- [Eggs](eggs.md)",
+					"<!-- markasten:backlinks:end -->",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo.",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Referenced by",
+					"- [Foo](foo.md)",
+					"<!-- markasten:backlinks:end -->",
+					"",
+					"Some text after the backlinks.",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam no longer mentions anything.",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksAppendWithFilesInSubDirectories() testCase {
+	return testCase{
+		name: "backlinks append with files in sub directories",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](./team/bar.md)",
+				},
+			},
+			{
+				name: "team/spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [bar](bar.md) twice, [here](./bar.md).",
+				},
+			},
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo and spam.",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar is mentioned by foo and spam.",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Backlinks",
+					"- [Foo](../foo.md)",
+					"  - Foo mentions bar",
+					"- [Spam](spam.md)",
+					"  - Spam mentions bar twice, here.",
+					"<!-- markasten:backlinks:end -->",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksAppendWithContextSnippets() testCase {
+	return testCase{
+		name:           "backlinks append with context snippets",
+		additionalArgs: []string{"--context-length", "40"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo has a long paragraph, which wraps",
+					"over more than one line before it mentions [bar](bar.md), and",
+					"then goes on for a while afterwards too.",
+					"",
+					"- A list item about [bar](bar.md).",
+					"- Another list item.",
+					"",
+					"## About [bar](bar.md)",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Backlinks",
+					"- [Foo](foo.md)",
+					"  - …it mentions bar, and then goes…",
+					"  - A list item about bar.",
+					"  - About bar",
+					"<!-- markasten:backlinks:end -->",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func backlinksAppendGroupedBySection() testCase {
+	return testCase{
+		name:           "backlinks append grouped by section",
+		additionalArgs: []string{"--context-length", "0"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](bar.md#second-section) and [[bar#First Section]].",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [bar](bar.md), [its first section](bar.md#first-section)",
+					"and [an old section](bar.md#old-section).",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"## First Section",
+					"## Second Section",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "bar.md",
+				contents: []string{
+					"# Bar",
+					"## First Section",
+					"## Second Section",
+					"",
+					"<!-- markasten:backlinks:start -->",
+					"## Backlinks",
+					"- [Spam](spam.md)",
+					"### First Section",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"### Second Section",
+					"- [Foo](foo.md)",
+					"### #old-section (heading not found)",
+					"- [Spam](spam.md)",
+					"<!-- markasten:backlinks:end -->",
+					"",
 				},
 			},
 		},
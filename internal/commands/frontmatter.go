@@ -0,0 +1,292 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	yamlFrontmatter = "yaml"
+	tomlFrontmatter = "toml"
+	jsonFrontmatter = "json"
+)
+
+var yamlErrorRegexp = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// frontmatter is the metadata at the top of a file.
+type frontmatter map[string]interface{}
+
+// frontmatterError is a problem with the frontmatter of a file, at a line
+// and column of the file.
+type frontmatterError struct {
+	line   int
+	column int
+	err    error
+}
+
+func (e *frontmatterError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.line, e.column, e.err)
+}
+
+func (e *frontmatterError) Unwrap() error {
+	return e.err
+}
+
+// invalidTagError is a value in frontmatter which cannot be interpreted as
+// tags. If the value is an item of a list, index is its index in the list,
+// otherwise it is -1.
+type invalidTagError struct {
+	key   string
+	index int
+	value interface{}
+}
+
+func (e *invalidTagError) Error() string {
+	if e.index >= 0 {
+		return fmt.Sprintf("%s contains %v, which is not a valid tag", e.key, e.value)
+	}
+	return fmt.Sprintf("%s is %v, which is not a list or string of tags", e.key, e.value)
+}
+
+// note is a file split into its frontmatter and the rest of its contents.
+type note struct {
+	// contents is the contents of the file, with any byte order mark
+	// removed and CRLF line endings replaced with LF.
+	contents    string
+	frontmatter frontmatter
+	// yaml is the node tree of YAML frontmatter, which is used to find the
+	// position of values in it.
+	yaml *yaml.Node
+	// yamlOffset is the number of lines before YAML frontmatter.
+	yamlOffset int
+	// errs are the problems found parsing the frontmatter.
+	errs []error
+}
+
+// parseNote parses the frontmatter at the top of a file. If the frontmatter
+// cannot be parsed, the note has no frontmatter and the problem is recorded
+// in its errs.
+func parseNote(fileBytes []byte) note {
+	contents := strings.TrimPrefix(string(fileBytes), "\ufeff")
+	contents = strings.ReplaceAll(contents, "\r\n", "\n")
+	n := note{contents: contents, frontmatter: frontmatter{}}
+	lines := strings.Split(contents, "\n")
+	format, start, end := frontmatterBounds(lines)
+	var err error
+	switch format {
+	case yamlFrontmatter:
+		err = n.parseYAML(lines, start, end)
+	case tomlFrontmatter:
+		var values map[string]interface{}
+		values, err = ParseTOML(strings.Join(lines[start+1:end], "\n"))
+		var tomlErr *tomlError
+		if errors.As(err, &tomlErr) {
+			err = &frontmatterError{line: start + tomlErr.line + 1, column: tomlErr.column, err: fmt.Errorf("invalid frontmatter: %w", tomlErr.err)}
+		}
+		n.frontmatter = values
+	case jsonFrontmatter:
+		object := strings.Join(lines[start:end+1], "\n")
+		err = json.Unmarshal([]byte(object), &n.frontmatter)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := position(object, int(syntaxErr.Offset))
+			line += start
+			err = &frontmatterError{line: line, column: column, err: fmt.Errorf("invalid frontmatter: json: %w", err)}
+		}
+	}
+	if err != nil {
+		debug("error parsing frontmatter: %s", err)
+		n.frontmatter = frontmatter{}
+		n.errs = append(n.errs, err)
+	}
+	return n
+}
+
+// parseYAML parses YAML frontmatter, which is between the lines with the
+// given indexes.
+func (n *note) parseYAML(lines []string, start int, end int) error {
+	n.yamlOffset = start + 1
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[start+1:end], "\n")), document); err != nil {
+		// yaml.v3 only reports the line of syntax errors, so the column
+		// is that of the first character on the line.
+		line, message := 0, strings.TrimPrefix(err.Error(), "yaml: ")
+		if match := yamlErrorRegexp.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = match[2]
+		}
+		line += n.yamlOffset
+		column := 1
+		if line <= len(lines) {
+			column += len(lines[line-1]) - len(strings.TrimLeft(lines[line-1], " \t"))
+		}
+		return &frontmatterError{line: line, column: column, err: fmt.Errorf("invalid frontmatter: %s", message)}
+	}
+	if len(document.Content) == 0 {
+		return nil
+	}
+	if err := document.Decode(&n.frontmatter); err != nil {
+		return &frontmatterError{
+			line:   document.Content[0].Line + n.yamlOffset,
+			column: document.Content[0].Column,
+			err:    fmt.Errorf("invalid frontmatter: %w", err),
+		}
+	}
+	n.yaml = document.Content[0]
+	return nil
+}
+
+// tags returns the tags in the given keys of the frontmatter of the note,
+// along with any problems with the frontmatter or its tags.
+func (n note) tags(keys []string) ([]string, []error) {
+	tags, tagErrs := n.frontmatter.tags(keys)
+	errs := append([]error{}, n.errs...)
+	for _, err := range tagErrs {
+		var tagErr *invalidTagError
+		if errors.As(err, &tagErr) {
+			if node := n.yamlValue(tagErr); node != nil {
+				err = &frontmatterError{line: node.Line + n.yamlOffset, column: node.Column, err: err}
+			}
+		}
+		errs = append(errs, err)
+	}
+	return tags, errs
+}
+
+// yamlValue returns the node in YAML frontmatter of the value which is not
+// a valid tag, if it can be found.
+func (n note) yamlValue(tagErr *invalidTagError) *yaml.Node {
+	if n.yaml == nil || n.yaml.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.yaml.Content); i += 2 {
+		if n.yaml.Content[i].Value != tagErr.key {
+			continue
+		}
+		value := n.yaml.Content[i+1]
+		if tagErr.index >= 0 && value.Kind == yaml.SequenceNode && tagErr.index < len(value.Content) {
+			return value.Content[tagErr.index]
+		}
+		return value
+	}
+	return nil
+}
+
+// title returns the title of the note, which is the title in its
+// frontmatter, or its first level one heading, or its file name without
+// its extension.
+func (n note) title(fileName string) string {
+	if title, ok := n.frontmatter["title"].(string); ok && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+	for _, h := range extractHeadings(n.contents) {
+		if h.level == 1 && h.text != "" {
+			return h.text
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+}
+
+// frontmatterBounds returns the format of the frontmatter at the top of a
+// file, if it has any, and the indexes of the lines the frontmatter starts
+// and ends on. Only blank lines can come before the frontmatter. YAML
+// frontmatter is enclosed in --- lines and can also end with a ... line,
+// TOML frontmatter is enclosed in +++ lines, and JSON frontmatter is an
+// object.
+func frontmatterBounds(lines []string) (string, int, int) {
+	start := 0
+	for start < len(lines) && strings.TrimSpace(strings.TrimPrefix(lines[start], "\ufeff")) == "" {
+		start++
+	}
+	if start == len(lines) {
+		return "", 0, 0
+	}
+	first := strings.TrimRight(strings.TrimPrefix(lines[start], "\ufeff"), " \t\r")
+	var format string
+	var delimiters []string
+	switch {
+	case first == "---":
+		format, delimiters = yamlFrontmatter, []string{"---", "..."}
+	case first == "+++":
+		format, delimiters = tomlFrontmatter, []string{"+++"}
+	case first == "{" || strings.HasPrefix(first, "{\""):
+		contents := strings.Join(lines[start:], "\n")
+		decoder := json.NewDecoder(strings.NewReader(contents))
+		var object json.RawMessage
+		if err := decoder.Decode(&object); err != nil {
+			// The rest of the file is treated as frontmatter, so that
+			// the problem with it is reported.
+			return jsonFrontmatter, start, len(lines) - 1
+		}
+		return jsonFrontmatter, start, start + strings.Count(contents[:decoder.InputOffset()], "\n")
+	default:
+		return "", 0, 0
+	}
+	for i := start + 1; i < len(lines); i++ {
+		if containsString(delimiters, strings.TrimRight(lines[i], " \t\r")) {
+			return format, start, i
+		}
+	}
+	return "", 0, 0
+}
+
+// position returns the 1-based line and column of a byte offset in text.
+func position(text string, offset int) (int, int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	line := strings.Count(text[:offset], "\n") + 1
+	column := offset - strings.LastIndex(text[:offset], "\n")
+	return line, column
+}
+
+// tags returns the tags in the given keys of the frontmatter. Each key can
+// either hold a list of tags, or a string of tags separated by commas or, if
+// there are no commas, spaces. Values which cannot be interpreted as tags
+// are returned as errors.
+func (fm frontmatter) tags(keys []string) ([]string, []error) {
+	var tags []string
+	var errs []error
+	add := func(tag string) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, key := range keys {
+		switch value := fm[key].(type) {
+		case nil:
+		case string:
+			if strings.Contains(value, ",") {
+				for _, tag := range strings.Split(value, ",") {
+					add(tag)
+				}
+			} else {
+				for _, tag := range strings.Fields(value) {
+					add(tag)
+				}
+			}
+		case []interface{}:
+			for i, item := range value {
+				switch item.(type) {
+				case string, int, float64, bool:
+					add(fmt.Sprint(item))
+				default:
+					errs = append(errs, &invalidTagError{key: key, index: i, value: item})
+				}
+			}
+		case int, float64, bool:
+			add(fmt.Sprint(value))
+		default:
+			errs = append(errs, &invalidTagError{key: key, index: -1, value: value})
+		}
+	}
+	return tags, errs
+}
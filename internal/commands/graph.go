@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphInputPath    *string
+	graphRoot         *string
+	graphOutputPath   *string
+	graphFormat       *string
+	graphTags         *bool
+	graphCheck        *bool
+	graphDebugEnabled *bool
+)
+
+const (
+	graphNoteNode = "note"
+	graphTagNode  = "tag"
+	graphLinkEdge = "link"
+	graphTagEdge  = "tag"
+)
+
+// noteGraph is the graph of notes, the links between them and, optionally,
+// their tags.
+type noteGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+}
+
+type graphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+func newGraphCommand() *cobra.Command {
+	graphCommand := &cobra.Command{
+		Use:          "graph",
+		Short:        "Export the graph of notes and the links between them",
+		RunE:         graphRunFn,
+		SilenceUsage: true,
+	}
+	graphInputPath = graphCommand.Flags().StringP("input", "i", "", "The location of the input files")
+	graphOutputPath = graphCommand.Flags().StringP("output", "o", "", "The location of an optional file to write the graph to, instead of stdout")
+	graphRoot = graphCommand.Flags().String("root", "", rootFlagUsage)
+	graphFormat = graphCommand.Flags().String("format", "dot", "The format of the graph, one of dot, graphml, json or mermaid")
+	graphTags = graphCommand.Flags().Bool("tags", false, "If set, tags will be included in the graph as nodes connected to the notes they are applied to")
+	graphCheck = graphCommand.Flags().Bool("check", false, checkFlagUsage)
+	graphDebugEnabled = graphCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	debugEnabled = graphDebugEnabled
+	return graphCommand
+}
+
+func graphRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = graphDebugEnabled
+	debug("graph called with -i %s and -o %s\n", *graphInputPath, *graphOutputPath)
+	var render func(noteGraph) string
+	switch *graphFormat {
+	case "dot":
+		render = renderGraphDOT
+	case "graphml":
+		render = renderGraphML
+	case "json":
+		render = renderGraphJSON
+	case "mermaid":
+		render = renderGraphMermaid
+	default:
+		return fmt.Errorf("unsupported format %q, expected one of dot, graphml, json or mermaid", *graphFormat)
+	}
+
+	inputDirEntries, err := newFullDirEntryList(*graphInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *graphInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults = excludeFile(searchResults, *graphOutputPath)
+	graph := buildNoteGraph(searchResults, *graphInputPath, linkRoot(*graphRoot, *graphInputPath), *graphTags)
+
+	return writeOutput(cmd, *graphOutputPath, render(graph), *graphCheck)
+}
+
+// buildNoteGraph returns the graph of the notes amongst the given files,
+// identified by their path relative to inputPath, with root-absolute links
+// resolved against root. Only links between notes are included as edges.
+func buildNoteGraph(dirEntries []fullDirEntry, inputPath string, root string, includeTags bool) noteGraph {
+	graph := noteGraph{}
+	idsByFile := make(map[string]string)
+	filesByTags := make(map[string][]indexedFile)
+	for _, dirEntry := range dirEntries {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		id := filepath.ToSlash(relativeTo(dirEntry.Name(), filepath.Join(inputPath, "graph")))
+		scrapedTags, title := scrapeTagsAndTitle(dirEntry.Name(), fileBytes)
+		idsByFile[absPath(dirEntry.Name())] = id
+		graph.Nodes = append(graph.Nodes, graphNode{ID: id, Label: title, Type: graphNoteNode})
+		filesByTags = appendFilesByTags(scrapedTags, filesByTags, title, id, nil)
+	}
+
+	backlinksByTarget, _ := findBacklinks(dirEntries, inputPath, root, nil)
+	for target, backlinks := range backlinksByTarget {
+		targetID, ok := idsByFile[target]
+		if !ok {
+			continue
+		}
+		for _, source := range uniqueSources(backlinks) {
+			sourceID, ok := idsByFile[absPath(source.fileName)]
+			if !ok {
+				continue
+			}
+			graph.Edges = append(graph.Edges, graphEdge{Source: sourceID, Target: targetID, Type: graphLinkEdge})
+		}
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Source != graph.Edges[j].Source {
+			return graph.Edges[i].Source < graph.Edges[j].Source
+		}
+		return graph.Edges[i].Target < graph.Edges[j].Target
+	})
+
+	if !includeTags {
+		return graph
+	}
+	var sortedTags []string
+	for tag := range filesByTags {
+		sortedTags = append(sortedTags, tag)
+	}
+	sort.Strings(sortedTags)
+	for _, tag := range sortedTags {
+		tagID := fmt.Sprintf("tag:%s", tag)
+		graph.Nodes = append(graph.Nodes, graphNode{ID: tagID, Label: tag, Type: graphTagNode})
+		for _, f := range filesByTags[tag] {
+			graph.Edges = append(graph.Edges, graphEdge{Source: f.fileName, Target: tagID, Type: graphTagEdge})
+		}
+	}
+	return graph
+}
+
+func renderGraphDOT(graph noteGraph) string {
+	quote := func(s string) string {
+		return fmt.Sprintf(`"%s"`, strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`))
+	}
+	lines := []string{"digraph markasten {"}
+	for _, node := range graph.Nodes {
+		attributes := fmt.Sprintf("label=%s", quote(node.Label))
+		if node.Type == graphTagNode {
+			attributes += ", shape=box"
+		}
+		lines = append(lines, fmt.Sprintf("  %s [%s];", quote(node.ID), attributes))
+	}
+	for _, edge := range graph.Edges {
+		line := fmt.Sprintf("  %s -> %s", quote(edge.Source), quote(edge.Target))
+		if edge.Type == graphTagEdge {
+			line += " [style=dashed]"
+		}
+		lines = append(lines, line+";")
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func renderGraphML(graph noteGraph) string {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type key struct {
+		ID       string `xml:"id,attr"`
+		For      string `xml:"for,attr"`
+		AttrName string `xml:"attr.name,attr"`
+		AttrType string `xml:"attr.type,attr"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   []data `xml:"data"`
+	}
+	type graphML struct {
+		XMLName xml.Name `xml:"graphml"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			ID          string `xml:"id,attr"`
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	document := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []key{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "nodeType", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "edgeType", For: "edge", AttrName: "type", AttrType: "string"},
+		},
+	}
+	document.Graph.ID = "markasten"
+	document.Graph.EdgeDefault = "directed"
+	for _, n := range graph.Nodes {
+		document.Graph.Nodes = append(document.Graph.Nodes, node{
+			ID:   n.ID,
+			Data: []data{{Key: "label", Value: n.Label}, {Key: "nodeType", Value: n.Type}},
+		})
+	}
+	for _, e := range graph.Edges {
+		document.Graph.Edges = append(document.Graph.Edges, edge{
+			Source: e.Source,
+			Target: e.Target,
+			Data:   []data{{Key: "edgeType", Value: e.Type}},
+		})
+	}
+	documentBytes, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return xml.Header + string(documentBytes) + "\n"
+}
+
+func renderGraphJSON(graph noteGraph) string {
+	if graph.Nodes == nil {
+		graph.Nodes = []graphNode{}
+	}
+	if graph.Edges == nil {
+		graph.Edges = []graphEdge{}
+	}
+	graphBytes, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(graphBytes) + "\n"
+}
+
+func renderGraphMermaid(graph noteGraph) string {
+	// Mermaid node IDs cannot contain most punctuation, so nodes are
+	// numbered and labelled instead.
+	mermaidIDs := make(map[string]string)
+	lines := []string{"graph LR"}
+	for n, node := range graph.Nodes {
+		mermaidID := fmt.Sprintf("n%d", n)
+		mermaidIDs[node.ID] = mermaidID
+		label := strings.ReplaceAll(node.Label, `"`, "#quot;")
+		if node.Type == graphTagNode {
+			lines = append(lines, fmt.Sprintf(`  %s(["%s"])`, mermaidID, label))
+		} else {
+			lines = append(lines, fmt.Sprintf(`  %s["%s"]`, mermaidID, label))
+		}
+	}
+	for _, edge := range graph.Edges {
+		arrow := "-->"
+		if edge.Type == graphTagEdge {
+			arrow = "-.->"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", mermaidIDs[edge.Source], arrow, mermaidIDs[edge.Target]))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
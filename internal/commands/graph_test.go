@@ -0,0 +1,252 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	for _, tc := range []testCase{
+		graphWithDOTFormat(),
+		graphWithDOTFormatAndTags(),
+		graphWithGraphMLFormat(),
+		graphWithJSONFormat(),
+		graphWithMermaidFormat(),
+		graphWithExistingMermaidGraph(),
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+			actualOutputFilePath := filepath.Join(inputDir, tc.outputFiles[0].name)
+
+			rootCmd := commands.NewRootCmd()
+			args := []string{
+				"graph",
+				"--debug",
+				"-i",
+				inputDir,
+				"-o",
+				actualOutputFilePath,
+			}
+			args = append(args, tc.additionalArgs...)
+			rootCmd.SetArgs(args)
+			require.NoError(t, rootCmd.Execute())
+
+			actualOutputBytes, err := os.ReadFile(actualOutputFilePath)
+			require.NoError(t, err)
+			require.Equal(t, strings.Join(tc.outputFiles[0].contents, "\n"), string(actualOutputBytes))
+		})
+	}
+}
+
+func graphInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- foo",
+				"- spam",
+				"---",
+				`# Foo "the first"`,
+				"Foo mentions [bar](team/bar.md), [bar again](team/bar.md) and [google](https://google.com).",
+			},
+		},
+		{
+			name: "team/bar.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- spam",
+				"---",
+				"# Bar",
+				"Bar mentions [foo](../foo.md) and [a missing file](missing.md).",
+			},
+		},
+		{
+			name: "spam.md",
+			contents: []string{
+				"Spam has no title, and mentions [bar](./team/bar.md).",
+			},
+		},
+	}
+}
+
+func graphWithDOTFormat() testCase {
+	return testCase{
+		name:       "graph with dot format",
+		inputFiles: graphInputFiles(),
+		outputFiles: []file{
+			{
+				name: "graph.dot",
+				contents: []string{
+					"digraph markasten {",
+					`  "foo.md" [label="Foo \"the first\""];`,
+					`  "spam.md" [label="spam"];`,
+					`  "team/bar.md" [label="Bar"];`,
+					`  "foo.md" -> "team/bar.md";`,
+					`  "spam.md" -> "team/bar.md";`,
+					`  "team/bar.md" -> "foo.md";`,
+					"}",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func graphWithDOTFormatAndTags() testCase {
+	return testCase{
+		name:           "graph with dot format and tags",
+		additionalArgs: []string{"--tags"},
+		inputFiles:     graphInputFiles(),
+		outputFiles: []file{
+			{
+				name: "graph.dot",
+				contents: []string{
+					"digraph markasten {",
+					`  "foo.md" [label="Foo \"the first\""];`,
+					`  "spam.md" [label="spam"];`,
+					`  "team/bar.md" [label="Bar"];`,
+					`  "tag:foo" [label="foo", shape=box];`,
+					`  "tag:spam" [label="spam", shape=box];`,
+					`  "foo.md" -> "team/bar.md";`,
+					`  "spam.md" -> "team/bar.md";`,
+					`  "team/bar.md" -> "foo.md";`,
+					`  "foo.md" -> "tag:foo" [style=dashed];`,
+					`  "foo.md" -> "tag:spam" [style=dashed];`,
+					`  "team/bar.md" -> "tag:spam" [style=dashed];`,
+					"}",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func graphWithGraphMLFormat() testCase {
+	return testCase{
+		name:           "graph with graphml format",
+		additionalArgs: []string{"--format", "graphml"},
+		inputFiles:     graphInputFiles()[1:],
+		outputFiles: []file{
+			{
+				name: "graph.graphml",
+				contents: []string{
+					`<?xml version="1.0" encoding="UTF-8"?>`,
+					`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`,
+					`  <key id="label" for="node" attr.name="label" attr.type="string"></key>`,
+					`  <key id="nodeType" for="node" attr.name="type" attr.type="string"></key>`,
+					`  <key id="edgeType" for="edge" attr.name="type" attr.type="string"></key>`,
+					`  <graph id="markasten" edgedefault="directed">`,
+					`    <node id="spam.md">`,
+					`      <data key="label">spam</data>`,
+					`      <data key="nodeType">note</data>`,
+					`    </node>`,
+					`    <node id="team/bar.md">`,
+					`      <data key="label">Bar</data>`,
+					`      <data key="nodeType">note</data>`,
+					`    </node>`,
+					`    <edge source="spam.md" target="team/bar.md">`,
+					`      <data key="edgeType">link</data>`,
+					`    </edge>`,
+					`  </graph>`,
+					`</graphml>`,
+					"",
+				},
+			},
+		},
+	}
+}
+
+func graphWithJSONFormat() testCase {
+	return testCase{
+		name:           "graph with json format",
+		additionalArgs: []string{"--format", "json", "--tags"},
+		inputFiles:     graphInputFiles()[1:],
+		outputFiles: []file{
+			{
+				name: "graph.json",
+				contents: []string{
+					"{",
+					`  "nodes": [`,
+					"    {",
+					`      "id": "spam.md",`,
+					`      "label": "spam",`,
+					`      "type": "note"`,
+					"    },",
+					"    {",
+					`      "id": "team/bar.md",`,
+					`      "label": "Bar",`,
+					`      "type": "note"`,
+					"    },",
+					"    {",
+					`      "id": "tag:spam",`,
+					`      "label": "spam",`,
+					`      "type": "tag"`,
+					"    }",
+					"  ],",
+					`  "edges": [`,
+					"    {",
+					`      "source": "spam.md",`,
+					`      "target": "team/bar.md",`,
+					`      "type": "link"`,
+					"    },",
+					"    {",
+					`      "source": "team/bar.md",`,
+					`      "target": "tag:spam",`,
+					`      "type": "tag"`,
+					"    }",
+					"  ]",
+					"}",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func graphWithExistingMermaidGraph() testCase {
+	tc := graphWithMermaidFormat()
+	tc.name = "graph with an existing mermaid graph in the input directory"
+	tc.outputFiles[0].name = "graph.md"
+	tc.inputFiles = append(tc.inputFiles, file{
+		name:     "graph.md",
+		contents: tc.outputFiles[0].contents,
+	})
+	return tc
+}
+
+func graphWithMermaidFormat() testCase {
+	return testCase{
+		name:           "graph with mermaid format",
+		additionalArgs: []string{"--format", "mermaid", "--tags"},
+		inputFiles:     graphInputFiles(),
+		outputFiles: []file{
+			{
+				name: "graph.mmd",
+				contents: []string{
+					"graph LR",
+					`  n0["Foo #quot;the first#quot;"]`,
+					`  n1["spam"]`,
+					`  n2["Bar"]`,
+					`  n3(["foo"])`,
+					`  n4(["spam"])`,
+					"  n0 --> n2",
+					"  n1 --> n2",
+					"  n2 --> n0",
+					"  n0 -.-> n3",
+					"  n0 -.-> n4",
+					"  n2 -.-> n4",
+					"",
+				},
+			},
+		},
+	}
+}
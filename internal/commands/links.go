@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	linksCheckInputPath    *string
+	linksCheckRoot         *string
+	linksCheckOutputPath   *string
+	linksCheckDebugEnabled *bool
+)
+
+// linkFinding is a problem found with a link in a file.
+type linkFinding struct {
+	fileName string
+	link     Link
+	message  string
+}
+
+func (f linkFinding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.fileName, f.link.Line, f.link.Column, f.message)
+}
+
+func newLinksCommand() *cobra.Command {
+	linksCommand := &cobra.Command{
+		Use: "links",
+	}
+	checkCommand := &cobra.Command{
+		Use:          "check",
+		Short:        "Report links to files or headings which do not exist",
+		RunE:         linksCheckRunFn,
+		SilenceUsage: true,
+	}
+	linksCheckDebugEnabled = checkCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	linksCheckInputPath = checkCommand.Flags().StringP("input", "i", "", "The location of the input files")
+	linksCheckRoot = checkCommand.Flags().String("root", "", rootFlagUsage)
+	linksCheckOutputPath = checkCommand.Flags().StringP("output", "o", "", "The location of an optional file to write the report to, instead of stdout")
+	linksCommand.AddCommand(checkCommand)
+	linksCommand.AddCommand(newLinksExternalCommand())
+	linksCommand.AddCommand(newLinksNormalizeCommand())
+	debugEnabled = linksCheckDebugEnabled
+	return linksCommand
+}
+
+func linksCheckRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = linksCheckDebugEnabled
+	debug("links check called with -i %s and -o %s\n", *linksCheckInputPath, *linksCheckOutputPath)
+	inputDirEntries, err := newFullDirEntryList(*linksCheckInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *linksCheckInputPath)
+	if err != nil {
+		panic(err)
+	}
+
+	contentsByFile := make(map[string][]byte)
+	for _, dirEntry := range searchResults {
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		contentsByFile[dirEntry.Name()] = fileBytes
+	}
+	checker := linkChecker{
+		root:    linkRoot(*linksCheckRoot, *linksCheckInputPath),
+		pages:   newWikiPages(searchResults, *linksCheckInputPath, contentsByFile),
+		anchors: make(map[string]map[string]heading),
+	}
+
+	var findings []linkFinding
+	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		for _, link := range ExtractLinks(string(contentsByFile[dirEntry.Name()])) {
+			if message := checker.check(dirEntry.Name(), link); message != "" {
+				findings = append(findings, linkFinding{
+					fileName: dirEntry.Name(),
+					link:     link,
+					message:  message,
+				})
+			}
+		}
+	}
+
+	var output io.Writer = cmd.OutOrStdout()
+	if *linksCheckOutputPath != "" {
+		outputFile, err := os.Create(*linksCheckOutputPath)
+		if err != nil {
+			panic(err)
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+	for _, finding := range findings {
+		writeOrPanic(output, fmt.Sprintf("%s\n", finding))
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d broken links", len(findings))
+	}
+	return nil
+}
+
+// linkChecker checks whether the targets of links exist.
+type linkChecker struct {
+	root    string
+	pages   wikiPages
+	anchors map[string]map[string]heading
+}
+
+// check returns a description of the problem with a link found in the
+// given file, or an empty string if there is none.
+func (c linkChecker) check(fileName string, link Link) string {
+	if link.Kind == WikiLink {
+		link = c.pages.orient(link)
+		target, ok := c.pages.resolveLink(link)
+		if !ok {
+			return fmt.Sprintf("wiki link to %s, which does not exist", link.Destination)
+		}
+		if _, heading, ok := strings.Cut(link.Destination, "#"); ok {
+			return c.checkFragment(target, headingSlug(heading), link.Destination)
+		}
+		return ""
+	}
+
+	u, err := url.Parse(link.Destination)
+	if err != nil {
+		return fmt.Sprintf("link to %s, which is not a valid URL", link.Destination)
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return ""
+	}
+	if u.Path == "" {
+		if u.Fragment == "" {
+			return ""
+		}
+		return c.checkFragment(fileName, u.Fragment, link.Destination)
+	}
+
+	target, _ := resolveLink(c.root, fileName, link.Destination)
+	actual, ok := actualPath(target)
+	if !ok {
+		return fmt.Sprintf("link to %s, which does not exist", link.Destination)
+	}
+	if actual != filepath.Clean(target) {
+		return fmt.Sprintf(
+			"link to %s, which differs in case from %s",
+			link.Destination,
+			filepath.ToSlash(relativeTo(actual, fileName)),
+		)
+	}
+	if u.Fragment == "" || !isMarkdownFile(target) {
+		return ""
+	}
+	return c.checkFragment(target, u.Fragment, link.Destination)
+}
+
+// checkFragment returns a description of the problem with a link to the
+// given fragment in the target file, or an empty string if there is none.
+func (c linkChecker) checkFragment(target string, fragment string, destination string) string {
+	anchors, ok := c.anchors[target]
+	if !ok {
+		fileBytes, err := os.ReadFile(target)
+		if err != nil {
+			panic(err)
+		}
+		anchors = extractAnchors(string(fileBytes))
+		c.anchors[target] = anchors
+	}
+	if _, ok := anchors[strings.ToLower(fragment)]; ok {
+		return ""
+	}
+	return fmt.Sprintf("link to %s, which does not match a heading in %s", destination, filepath.Base(target))
+}
+
+// actualPath returns the path of the file which matches the given path
+// case-insensitively, with the case of each element as it is on disk. An
+// exact match is preferred if there is more than one.
+func actualPath(path string) (string, bool) {
+	path = filepath.Clean(path)
+	dir, base := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if base == "" || base == "." || base == ".." || dir == path {
+		return path, true
+	}
+	actualDir, ok := actualPath(dir)
+	if !ok {
+		return "", false
+	}
+	entries, err := os.ReadDir(actualDir)
+	if err != nil {
+		return "", false
+	}
+	match := ""
+	for _, entry := range entries {
+		if entry.Name() == base {
+			return filepath.Join(actualDir, base), true
+		}
+		if match == "" && strings.EqualFold(entry.Name(), base) {
+			match = entry.Name()
+		}
+	}
+	if match == "" {
+		return "", false
+	}
+	return filepath.Join(actualDir, match), true
+}
@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	linksExternalInputPath     *string
+	linksExternalOutputPath    *string
+	linksExternalGroupByDomain *bool
+	linksExternalCheck         *bool
+	linksExternalConcurrency   *int
+	linksExternalTimeout       *time.Duration
+	linksExternalRetries       *int
+	linksExternalRetryDelay    *time.Duration
+	linksExternalRateLimit     *float64
+	linksExternalDebugEnabled  *bool
+)
+
+// externalLink is an external URL, along with the places it is used.
+type externalLink struct {
+	url    string
+	domain string
+	usages []linkFinding
+	status urlStatus
+}
+
+// urlStatus is the result of checking an external URL.
+type urlStatus struct {
+	code int
+	err  error
+}
+
+func (s urlStatus) failed() bool {
+	return s.err != nil || s.code >= 400
+}
+
+func (s urlStatus) String() string {
+	if s.err != nil {
+		return fmt.Sprintf("error: %s", s.err)
+	}
+	return fmt.Sprintf("%d %s", s.code, http.StatusText(s.code))
+}
+
+func newLinksExternalCommand() *cobra.Command {
+	externalCommand := &cobra.Command{
+		Use:          "external",
+		Short:        "List the external URLs linked to, and optionally check that they work",
+		RunE:         linksExternalRunFn,
+		SilenceUsage: true,
+	}
+	linksExternalDebugEnabled = externalCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	linksExternalInputPath = externalCommand.Flags().StringP("input", "i", "", "The location of the input files")
+	linksExternalOutputPath = externalCommand.Flags().StringP("output", "o", "", "The location of an optional file to write the report to, instead of stdout")
+	linksExternalGroupByDomain = externalCommand.Flags().Bool("group-by-domain", false, "If set, URLs will be grouped by their domain")
+	linksExternalCheck = externalCommand.Flags().Bool("check", false, "If set, each URL will be requested, and those which fail will be reported")
+	linksExternalConcurrency = externalCommand.Flags().Int("concurrency", 8, "The maximum number of URLs to check at once")
+	linksExternalTimeout = externalCommand.Flags().Duration("timeout", 10*time.Second, "The timeout for each request")
+	linksExternalRetries = externalCommand.Flags().Int("retries", 2, "The number of times to retry requests which fail with an error, 429 or 5xx status")
+	linksExternalRetryDelay = externalCommand.Flags().Duration("retry-delay", 500*time.Millisecond, "The delay before the first retry of a request, which doubles for each following retry")
+	linksExternalRateLimit = externalCommand.Flags().Float64("rate-limit", 2, "The maximum number of requests per second to each domain, or 0 for no limit")
+	return externalCommand
+}
+
+func linksExternalRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = linksExternalDebugEnabled
+	debug("links external called with -i %s and -o %s\n", *linksExternalInputPath, *linksExternalOutputPath)
+	inputDirEntries, err := newFullDirEntryList(*linksExternalInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *linksExternalInputPath)
+	if err != nil {
+		panic(err)
+	}
+
+	externalLinksByURL := make(map[string]*externalLink)
+	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		for _, link := range ExtractLinks(string(fileBytes)) {
+			if link.Kind == WikiLink {
+				continue
+			}
+			u, err := url.Parse(link.Destination)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				continue
+			}
+			e, ok := externalLinksByURL[link.Destination]
+			if !ok {
+				e = &externalLink{url: link.Destination, domain: strings.ToLower(u.Hostname())}
+				externalLinksByURL[link.Destination] = e
+			}
+			e.usages = append(e.usages, linkFinding{fileName: dirEntry.Name(), link: link})
+		}
+	}
+	var externalLinks []*externalLink
+	for _, e := range externalLinksByURL {
+		externalLinks = append(externalLinks, e)
+	}
+	sort.Slice(externalLinks, func(i, j int) bool {
+		if *linksExternalGroupByDomain && externalLinks[i].domain != externalLinks[j].domain {
+			return externalLinks[i].domain < externalLinks[j].domain
+		}
+		return externalLinks[i].url < externalLinks[j].url
+	})
+
+	failures := 0
+	if *linksExternalCheck {
+		checker := newURLChecker(*linksExternalTimeout, *linksExternalRetries, *linksExternalRetryDelay, *linksExternalRateLimit)
+		checker.checkAll(externalLinks, *linksExternalConcurrency)
+		for _, e := range externalLinks {
+			if e.status.failed() {
+				failures++
+			}
+		}
+	}
+
+	var output io.Writer = cmd.OutOrStdout()
+	if *linksExternalOutputPath != "" {
+		outputFile, err := os.Create(*linksExternalOutputPath)
+		if err != nil {
+			panic(err)
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+	writeOrPanic(output, renderExternalLinks(externalLinks, *linksExternalGroupByDomain, *linksExternalCheck))
+	if failures > 0 {
+		return fmt.Errorf("found %d broken external links", failures)
+	}
+	return nil
+}
+
+// renderExternalLinks lists each URL, followed by the file and line of each
+// place it is used. If the URLs have been checked, the status of each is
+// included after it.
+func renderExternalLinks(externalLinks []*externalLink, groupByDomain bool, checked bool) string {
+	var lines []string
+	indent := ""
+	domain := ""
+	for _, e := range externalLinks {
+		if groupByDomain {
+			indent = "  "
+			if e.domain != domain {
+				domain = e.domain
+				lines = append(lines, domain)
+			}
+		}
+		line := indent + e.url
+		if checked {
+			line = fmt.Sprintf("%s (%s)", line, e.status)
+		}
+		lines = append(lines, line)
+		for _, usage := range e.usages {
+			lines = append(lines, fmt.Sprintf("%s  %s:%d", indent, usage.fileName, usage.link.Line))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// maxDiscardedBodySize is the most of a response body which is read before
+// it is closed. Smaller bodies are read in full so that the connection can
+// be reused, but larger ones, such as downloads, are not.
+const maxDiscardedBodySize = 64 * 1024
+
+// urlChecker requests external URLs, limiting the rate of requests made to
+// each domain.
+type urlChecker struct {
+	client     *http.Client
+	retries    int
+	retryDelay time.Duration
+	interval   time.Duration
+
+	mutex        sync.Mutex
+	nextRequests map[string]time.Time
+}
+
+func newURLChecker(timeout time.Duration, retries int, retryDelay time.Duration, rateLimit float64) *urlChecker {
+	checker := &urlChecker{
+		client:       &http.Client{Timeout: timeout},
+		retries:      retries,
+		retryDelay:   retryDelay,
+		nextRequests: make(map[string]time.Time),
+	}
+	if rateLimit > 0 {
+		checker.interval = time.Duration(float64(time.Second) / rateLimit)
+	}
+	return checker
+}
+
+// checkAll checks each of the given links, with at most concurrency of
+// them being checked at once.
+func (c *urlChecker) checkAll(externalLinks []*externalLink, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	queue := make(chan *externalLink)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range queue {
+				e.status = c.check(e.url, e.domain)
+				debug("checked %s: %s", e.url, e.status)
+			}
+		}()
+	}
+	for _, e := range externalLinks {
+		queue <- e
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// check requests a URL using HEAD and, if that fails, GET, since some
+// servers do not support HEAD requests. Requests which fail with an error,
+// or a status indicating the failure may be temporary, are retried after a
+// delay which doubles with each retry.
+func (c *urlChecker) check(rawURL string, domain string) urlStatus {
+	var status urlStatus
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.retryDelay << (attempt - 1))
+		}
+		status = c.request(http.MethodHead, rawURL, domain)
+		if status.failed() {
+			status = c.request(http.MethodGet, rawURL, domain)
+		}
+		if status.err == nil && status.code != http.StatusTooManyRequests && status.code < 500 {
+			break
+		}
+	}
+	return status
+}
+
+func (c *urlChecker) request(method string, rawURL string, domain string) urlStatus {
+	c.wait(domain)
+	request, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return urlStatus{err: err}
+	}
+	request.Header.Set("User-Agent", "markasten")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return urlStatus{err: err}
+	}
+	defer response.Body.Close()
+	// The body is discarded, so that the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxDiscardedBodySize))
+	return urlStatus{code: response.StatusCode}
+}
+
+// wait blocks until a request can be made to the domain without exceeding
+// the rate limit.
+func (c *urlChecker) wait(domain string) {
+	if c.interval == 0 {
+		return
+	}
+	c.mutex.Lock()
+	now := time.Now()
+	next := c.nextRequests[domain]
+	if next.Before(now) {
+		next = now
+	}
+	c.nextRequests[domain] = next.Add(c.interval)
+	c.mutex.Unlock()
+	time.Sleep(time.Until(next))
+}
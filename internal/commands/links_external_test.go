@@ -0,0 +1,314 @@
+package commands_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinksExternal(t *testing.T) {
+	for _, tc := range []testCase{
+		linksExternalInventory(),
+		linksExternalGroupedByDomain(),
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+
+			rootCmd := commands.NewRootCmd()
+			output := &bytes.Buffer{}
+			rootCmd.SetOut(output)
+			args := []string{
+				"links",
+				"external",
+				"--debug",
+				"-i",
+				inputDir,
+			}
+			args = append(args, tc.additionalArgs...)
+			rootCmd.SetArgs(args)
+			require.NoError(t, rootCmd.Execute())
+
+			actualOutput := strings.ReplaceAll(output.String(), inputDir+string(filepath.Separator), "")
+			require.Equal(t, strings.Join(tc.outputFiles[0].contents, "\n"), actualOutput)
+		})
+	}
+}
+
+func TestLinksExternalVerify(t *testing.T) {
+	var mutex sync.Mutex
+	flakyRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/flaky":
+			mutex.Lock()
+			defer mutex.Unlock()
+			flakyRequests++
+			if flakyRequests <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	inputDir := writeFiles(t, []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"# Foo",
+				"Foo links to [ok](" + server.URL + "/ok) and [missing](" + server.URL + "/missing).",
+			},
+		},
+		{
+			name: "bar.md",
+			contents: []string{
+				"# Bar",
+				"Bar links to <" + server.URL + "/no-head>,",
+				"[flaky](" + server.URL + "/flaky) and [ok](" + server.URL + "/ok).",
+			},
+		},
+	}, "markasten-input")
+
+	rootCmd := commands.NewRootCmd()
+	output := &bytes.Buffer{}
+	rootCmd.SetOut(output)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{
+		"links",
+		"external",
+		"--check",
+		"--rate-limit",
+		"100",
+		"--retry-delay",
+		"1ms",
+		"-i",
+		inputDir,
+	})
+	require.EqualError(t, rootCmd.Execute(), "found 1 broken external links")
+
+	actualOutput := strings.ReplaceAll(output.String(), inputDir+string(filepath.Separator), "")
+	actualOutput = strings.ReplaceAll(actualOutput, server.URL, "http://server")
+	require.Equal(t, strings.Join([]string{
+		"http://server/flaky (200 OK)",
+		"  bar.md:3",
+		"http://server/missing (404 Not Found)",
+		"  foo.md:2",
+		"http://server/no-head (200 OK)",
+		"  bar.md:2",
+		"http://server/ok (200 OK)",
+		"  bar.md:3",
+		"  foo.md:2",
+		"",
+	}, "\n"), actualOutput)
+}
+
+func TestLinksExternalVerifyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	output, err := verifyExternalLinks(t, server.URL, []string{"/slow"}, "--timeout", "50ms", "--retries", "0")
+	require.EqualError(t, err, "found 1 broken external links")
+	require.Contains(t, output, "http://server/slow (error: ")
+	require.Contains(t, output, "Client.Timeout exceeded")
+}
+
+func TestLinksExternalVerifyLargeBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// The body is written until the client stops reading it.
+		chunk := bytes.Repeat([]byte("x"), 1024)
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			default:
+			}
+		}
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	output, err := verifyExternalLinks(t, server.URL, []string{"/download"}, "--timeout", "5s", "--retries", "0")
+	require.NoError(t, err)
+	require.Equal(t, "http://server/download (200 OK)\n  foo.md:1\n", output)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestLinksExternalVerifyConcurrency(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	paths := []string{"/a", "/b", "/c", "/d", "/e", "/f"}
+	_, err := verifyExternalLinks(t, server.URL, paths, "--concurrency", "2", "--rate-limit", "0")
+	require.NoError(t, err)
+	require.Equal(t, 2, maxInFlight)
+}
+
+func TestLinksExternalVerifyRateLimit(t *testing.T) {
+	var mutex sync.Mutex
+	var requestTimes []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requestTimes = append(requestTimes, time.Now())
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	paths := []string{"/a", "/b", "/c", "/d"}
+	_, err := verifyExternalLinks(t, server.URL, paths, "--concurrency", "4", "--rate-limit", "20")
+	require.NoError(t, err)
+
+	// Requests to the same domain are spaced by 1s / 20 = 50ms, even though
+	// they could all be made at once.
+	require.Len(t, requestTimes, 4)
+	for i := 1; i < len(requestTimes); i++ {
+		require.GreaterOrEqual(t, requestTimes[i].Sub(requestTimes[i-1]), 40*time.Millisecond)
+	}
+}
+
+// verifyExternalLinks runs links external --verify on a note linking to each
+// of the given paths on a server, and returns its output with the server URL
+// replaced by http://server.
+func verifyExternalLinks(t *testing.T, serverURL string, paths []string, additionalArgs ...string) (string, error) {
+	var links []string
+	for _, path := range paths {
+		links = append(links, "<"+serverURL+path+">")
+	}
+	inputDir := writeFiles(t, []file{
+		{
+			name:     "foo.md",
+			contents: []string{strings.Join(links, " ")},
+		},
+	}, "markasten-input")
+
+	rootCmd := commands.NewRootCmd()
+	output := &bytes.Buffer{}
+	rootCmd.SetOut(output)
+	rootCmd.SetErr(&bytes.Buffer{})
+	args := []string{
+		"links",
+		"external",
+		"--check",
+		"-i",
+		inputDir,
+	}
+	rootCmd.SetArgs(append(args, additionalArgs...))
+	err := rootCmd.Execute()
+
+	actualOutput := strings.ReplaceAll(output.String(), inputDir+string(filepath.Separator), "")
+	return strings.ReplaceAll(actualOutput, serverURL, "http://server"), err
+}
+
+func linksExternalInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"# Foo",
+				"Foo links to [example](https://example.com/foo) and [bar](bar.md).",
+				"And to [other](http://other.org) and <https://example.com/bar>.",
+			},
+		},
+		{
+			name: "team/bar.md",
+			contents: []string{
+				"# Bar",
+				"Bar links to [example][example], [[Foo]] and [mail](mailto:bar@example.com).",
+				"```",
+				"[in code](https://example.com/code)",
+				"```",
+				"",
+				"[example]: https://example.com/foo",
+			},
+		},
+	}
+}
+
+func linksExternalInventory() testCase {
+	return testCase{
+		name:       "links external inventory",
+		inputFiles: linksExternalInputFiles(),
+		outputFiles: []file{
+			{
+				contents: []string{
+					"http://other.org",
+					"  foo.md:3",
+					"https://example.com/bar",
+					"  foo.md:3",
+					"https://example.com/foo",
+					"  foo.md:2",
+					"  team/bar.md:2",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func linksExternalGroupedByDomain() testCase {
+	return testCase{
+		name:           "links external grouped by domain",
+		additionalArgs: []string{"--group-by-domain"},
+		inputFiles:     linksExternalInputFiles(),
+		outputFiles: []file{
+			{
+				contents: []string{
+					"example.com",
+					"  https://example.com/bar",
+					"    foo.md:3",
+					"  https://example.com/foo",
+					"    foo.md:2",
+					"    team/bar.md:2",
+					"other.org",
+					"  http://other.org",
+					"    foo.md:3",
+					"",
+				},
+			},
+		},
+	}
+}
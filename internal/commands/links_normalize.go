@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	linksNormalizeInputPath    *string
+	linksNormalizeRoot         *string
+	linksNormalizeStyle        *string
+	linksNormalizeExtensions   *string
+	linksNormalizeDryRun       *bool
+	linksNormalizeDebugEnabled *bool
+)
+
+const (
+	relativeLinkStyle     = "relative"
+	wikiLinkStyle         = "wiki"
+	rootAbsoluteLinkStyle = "root-absolute"
+
+	keepExtensions   = "keep"
+	addExtensions    = "add"
+	removeExtensions = "remove"
+)
+
+func newLinksNormalizeCommand() *cobra.Command {
+	normalizeCommand := &cobra.Command{
+		Use:          "normalize",
+		Short:        "Rewrite the links between notes in a consistent style",
+		RunE:         linksNormalizeRunFn,
+		SilenceUsage: true,
+	}
+	linksNormalizeDebugEnabled = normalizeCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	linksNormalizeInputPath = normalizeCommand.Flags().StringP("input", "i", "", "The location of the input files")
+	linksNormalizeRoot = normalizeCommand.Flags().String("root", "", rootFlagUsage)
+	linksNormalizeStyle = normalizeCommand.Flags().String("style", relativeLinkStyle, "The style to rewrite links in, one of relative, wiki or root-absolute")
+	linksNormalizeExtensions = normalizeCommand.Flags().String("extensions", addExtensions, "Whether links to notes are written with their .md extension in the relative and root-absolute styles, one of keep, add or remove")
+	linksNormalizeDryRun = normalizeCommand.Flags().Bool("dry-run", false, "If set, the planned changes will be printed instead of made")
+	return normalizeCommand
+}
+
+func linksNormalizeRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = linksNormalizeDebugEnabled
+	debug("links normalize called with -i %s and --style %s\n", *linksNormalizeInputPath, *linksNormalizeStyle)
+	switch *linksNormalizeStyle {
+	case relativeLinkStyle, wikiLinkStyle, rootAbsoluteLinkStyle:
+	default:
+		return fmt.Errorf("unsupported style %q, expected one of relative, wiki or root-absolute", *linksNormalizeStyle)
+	}
+	switch *linksNormalizeExtensions {
+	case keepExtensions, addExtensions, removeExtensions:
+	default:
+		return fmt.Errorf("unsupported extensions %q, expected one of keep, add or remove", *linksNormalizeExtensions)
+	}
+	inputDirEntries, err := newFullDirEntryList(*linksNormalizeInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *linksNormalizeInputPath)
+	if err != nil {
+		panic(err)
+	}
+	contentsByFile := make(map[string][]byte)
+	for _, dirEntry := range searchResults {
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		contentsByFile[dirEntry.Name()] = fileBytes
+	}
+	root := linkRoot(*linksNormalizeRoot, *linksNormalizeInputPath)
+	normalizer := linkNormalizer{
+		inputPath:  *linksNormalizeInputPath,
+		root:       root,
+		style:      *linksNormalizeStyle,
+		extensions: *linksNormalizeExtensions,
+		pages:      newWikiPages(searchResults, *linksNormalizeInputPath, contentsByFile),
+		anchors:    make(map[string]map[string]heading),
+	}
+
+	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		contents := string(contentsByFile[dirEntry.Name()])
+		edits := normalizer.planEdits(dirEntry.Name(), contents)
+		if *linksNormalizeDryRun {
+			for _, edit := range edits {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s:%d: %s -> %s\n", edit.fileName, edit.line, edit.oldDestination, edit.newDestination)
+			}
+			continue
+		}
+		if len(edits) == 0 {
+			continue
+		}
+		debug("normalizing %d links in %s", len(edits), dirEntry.Name())
+		if err := os.WriteFile(dirEntry.Name(), []byte(applyLinkEdits(contents, edits)), 0644); err != nil {
+			panic(err)
+		}
+	}
+	return nil
+}
+
+// linkNormalizer plans the edits needed to rewrite links between notes in
+// a given style. Wiki links are relative to the input path, and
+// root-absolute links to the root. Links to notes in the relative and
+// root-absolute styles keep, add or remove the .md extension, depending on
+// extensions.
+type linkNormalizer struct {
+	inputPath  string
+	root       string
+	style      string
+	extensions string
+	pages      wikiPages
+	anchors    map[string]map[string]heading
+}
+
+// planEdits returns the edits needed to rewrite the links in a file which
+// refer to other files in the input, in the style of the normalizer. Links
+// to external URLs, images and links to files which do not exist are left
+// unchanged.
+func (n linkNormalizer) planEdits(fileName string, contents string) []linkEdit {
+	var edits []linkEdit
+	edited := make(map[int]bool)
+	for _, link := range ExtractLinks(contents) {
+		if link.Image || edited[link.destinationStart] {
+			continue
+		}
+		link = n.pages.orient(link)
+		target, fragment, ok := n.resolve(fileName, link)
+		if !ok {
+			continue
+		}
+
+		edit := linkEdit{
+			fileName: fileName,
+			line:     link.Line,
+			start:    link.start,
+			end:      link.end,
+		}
+		switch {
+		case n.style == wikiLinkStyle && link.Kind == WikiLink:
+			// Labelled wiki links are put in the GitHub wiki order,
+			// keeping the page name they were written with.
+			if link.textStart == link.textEnd || link.textStart < link.destinationStart {
+				continue
+			}
+			edit.newDestination = fmt.Sprintf("[[%s|%s]]", link.Text, link.Destination)
+		case n.style == wikiLinkStyle:
+			// Reference links, links with a title or query string, and
+			// links to files other than notes cannot be written as wiki
+			// links.
+			if link.Kind != InlineLink || link.Title != "" || strings.Contains(link.Destination, "?") || !isMarkdownFile(target) {
+				continue
+			}
+			edit.newDestination = n.wikiLink(target, fragment, link.Text)
+		case link.Kind == WikiLink:
+			edit.newDestination = fmt.Sprintf("[%s](%s)", link.Text, n.destination(fileName, target, headingSlug(fragment), "", false))
+		default:
+			path, suffix := link.Destination, ""
+			if i := strings.IndexAny(link.Destination, "?#"); i >= 0 {
+				path, suffix = link.Destination[:i], link.Destination[i:]
+			}
+			edit.start = link.destinationStart
+			edit.end = link.destinationEnd
+			edit.newDestination = n.destination(fileName, target, "", suffix, filepath.Ext(path) != "")
+		}
+		edit.oldDestination = contents[edit.start:edit.end]
+		if edit.newDestination == edit.oldDestination {
+			continue
+		}
+		edited[link.destinationStart] = true
+		edits = append(edits, edit)
+	}
+	return edits
+}
+
+// resolve returns the file a link refers to, and the fragment of it the
+// link refers to, if any. Links without an extension refer to the note with
+// the .md extension if there is no file without one. If the link is not to
+// a file in the input, false is returned.
+func (n linkNormalizer) resolve(fileName string, link Link) (string, string, bool) {
+	if link.Kind == WikiLink {
+		target, ok := n.pages.resolveLink(link)
+		if !ok {
+			return "", "", false
+		}
+		_, fragment, _ := strings.Cut(link.Destination, "#")
+		return target, fragment, true
+	}
+	target, ok := resolveLink(n.root, fileName, link.Destination)
+	if !ok {
+		return "", "", false
+	}
+	if !isFile(target) {
+		// Links to notes can leave out the .md extension, as GitHub and
+		// most static site generators resolve them.
+		if filepath.Ext(target) != "" || !isFile(target+".md") {
+			return "", "", false
+		}
+		target += ".md"
+	}
+	u, err := url.Parse(link.Destination)
+	if err != nil {
+		return "", "", false
+	}
+	return target, u.Fragment, true
+}
+
+// destination returns the destination of a link from fileName to target,
+// in the relative or root-absolute style of the normalizer. If the link is
+// to a note, hasExtension is whether the original link included its
+// extension, which is kept if the normalizer keeps extensions.
+func (n linkNormalizer) destination(fileName string, target string, fragment string, suffix string, hasExtension bool) string {
+	destination := linkPath(n.root, fileName, target, n.style == rootAbsoluteLinkStyle)
+	if isMarkdownFile(target) && (n.extensions == removeExtensions || (n.extensions == keepExtensions && !hasExtension)) {
+		destination = strings.TrimSuffix(destination, filepath.Ext(destination))
+	}
+	if strings.Contains(destination, " ") {
+		destination = (&url.URL{Path: destination}).EscapedPath()
+	}
+	if fragment != "" {
+		suffix = "#" + fragment
+	}
+	return destination + suffix
+}
+
+// wikiLink returns a wiki link to target, using the text of the heading the
+// fragment refers to, if there is one. The page is referred to by its file
+// name if it is unique, otherwise by its path relative to the root.
+func (n linkNormalizer) wikiLink(target string, fragment string, text string) string {
+	page := makeWikiLink(filepath.Base(target))
+	if fileName, ok := n.pages.byName[wikiPageKey(page)]; !ok || absPath(fileName) != absPath(target) {
+		page = makeWikiLink(filepath.ToSlash(relativeTo(target, filepath.Join(n.inputPath, "root"))))
+	}
+	destination := page
+	if fragment != "" {
+		anchors, ok := n.anchors[target]
+		if !ok {
+			fileBytes, err := os.ReadFile(target)
+			if err != nil {
+				panic(err)
+			}
+			anchors = extractAnchors(string(fileBytes))
+			n.anchors[target] = anchors
+		}
+		if h, ok := anchors[strings.ToLower(fragment)]; ok {
+			fragment = h.text
+		}
+		destination = fmt.Sprintf("%s#%s", page, fragment)
+	}
+	if text == "" || text == destination {
+		return fmt.Sprintf("[[%s]]", destination)
+	}
+	// GitHub wikis write the label before the page name.
+	return fmt.Sprintf("[[%s|%s]]", text, destination)
+}
+
+// isFile returns true if path is a file which exists.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,314 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinksNormalize(t *testing.T) {
+	for _, tc := range []testCase{
+		linksNormalizeToRelative(),
+		linksNormalizeToWiki(),
+		linksNormalizeToRootAbsolute(),
+		linksNormalizeToRelativeKeepingExtensions(),
+		linksNormalizeToRootAbsoluteWithoutExtensions(),
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+
+			// Normalizing links which are already normalized should not
+			// change them.
+			for i := 0; i < 2; i++ {
+				rootCmd := commands.NewRootCmd()
+				args := []string{
+					"links",
+					"normalize",
+					"--debug",
+					"-i",
+					inputDir,
+				}
+				args = append(args, tc.additionalArgs...)
+				rootCmd.SetArgs(args)
+				require.NoError(t, rootCmd.Execute())
+			}
+
+			for _, outputFile := range tc.outputFiles {
+				actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, outputFile.name))
+				require.NoError(t, err)
+				require.Equal(t, strings.Join(outputFile.contents, "\n"), string(actualOutputBytes))
+			}
+		})
+	}
+}
+
+func linksNormalizeInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"# Foo",
+				"Foo mentions [bar](team/bar.md#bar-heading), [[spam]] and [[team/bar#Bar Heading|bar's heading]].",
+				"It also mentions [bar][ref], [an image](image.png), [google](https://google.com) and [eggs](eggs.md).",
+				"",
+				"[ref]: /team/bar.md",
+			},
+		},
+		{
+			name: "team/bar.md",
+			contents: []string{
+				"# Bar",
+				"Bar mentions [foo](/foo.md) and [spam](../spam.md \"Spam\").",
+				"## Bar Heading",
+			},
+		},
+		{
+			name: "spam.md",
+			contents: []string{
+				"# Spam",
+				"Spam mentions [bar](team/bar) without its extension.",
+			},
+		},
+		{
+			name: "image.png",
+		},
+	}
+}
+
+func linksNormalizeToRelative() testCase {
+	return testCase{
+		name:           "links normalize to relative",
+		additionalArgs: []string{"--style", "relative"},
+		inputFiles:     linksNormalizeInputFiles(),
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](team/bar.md#bar-heading), [spam](spam.md) and [bar's heading](team/bar.md#bar-heading).",
+					"It also mentions [bar][ref], [an image](image.png), [google](https://google.com) and [eggs](eggs.md).",
+					"",
+					"[ref]: team/bar.md",
+				},
+			},
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar mentions [foo](../foo.md) and [spam](../spam.md \"Spam\").",
+					"## Bar Heading",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [bar](team/bar.md) without its extension.",
+				},
+			},
+		},
+	}
+}
+
+func linksNormalizeToWiki() testCase {
+	return testCase{
+		name:           "links normalize to wiki",
+		additionalArgs: []string{"--style", "wiki"},
+		inputFiles:     linksNormalizeInputFiles(),
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [[bar|bar#Bar Heading]], [[spam]] and [[bar's heading|team/bar#Bar Heading]].",
+					"It also mentions [bar][ref], [an image](image.png), [google](https://google.com) and [eggs](eggs.md).",
+					"",
+					"[ref]: /team/bar.md",
+				},
+			},
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar mentions [[foo]] and [spam](../spam.md \"Spam\").",
+					"## Bar Heading",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [[bar]] without its extension.",
+				},
+			},
+		},
+	}
+}
+
+func linksNormalizeToRootAbsolute() testCase {
+	return testCase{
+		name:           "links normalize to root-absolute",
+		additionalArgs: []string{"--style", "root-absolute"},
+		inputFiles:     linksNormalizeInputFiles(),
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](/team/bar.md#bar-heading), [spam](/spam.md) and [bar's heading](/team/bar.md#bar-heading).",
+					"It also mentions [bar][ref], [an image](/image.png), [google](https://google.com) and [eggs](eggs.md).",
+					"",
+					"[ref]: /team/bar.md",
+				},
+			},
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar mentions [foo](/foo.md) and [spam](/spam.md \"Spam\").",
+					"## Bar Heading",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [bar](/team/bar.md) without its extension.",
+				},
+			},
+		},
+	}
+}
+
+func linksNormalizeToRelativeKeepingExtensions() testCase {
+	return testCase{
+		name:           "links normalize to relative keeping extensions",
+		additionalArgs: []string{"--style", "relative", "--extensions", "keep"},
+		inputFiles:     linksNormalizeInputFiles(),
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](team/bar.md#bar-heading), [spam](spam) and [bar's heading](team/bar#bar-heading).",
+					"It also mentions [bar][ref], [an image](image.png), [google](https://google.com) and [eggs](eggs.md).",
+					"",
+					"[ref]: team/bar.md",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [bar](team/bar) without its extension.",
+				},
+			},
+		},
+	}
+}
+
+func linksNormalizeToRootAbsoluteWithoutExtensions() testCase {
+	return testCase{
+		name:           "links normalize to root-absolute without extensions",
+		additionalArgs: []string{"--style", "root-absolute", "--extensions", "remove"},
+		inputFiles:     linksNormalizeInputFiles(),
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](/team/bar#bar-heading), [spam](/spam) and [bar's heading](/team/bar#bar-heading).",
+					"It also mentions [bar][ref], [an image](/image.png), [google](https://google.com) and [eggs](eggs.md).",
+					"",
+					"[ref]: /team/bar",
+				},
+			},
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"Bar mentions [foo](/foo) and [spam](/spam \"Spam\").",
+					"## Bar Heading",
+				},
+			},
+		},
+	}
+}
+
+func TestLinksNormalizeInRepo(t *testing.T) {
+	for _, tc := range []testCase{
+		{
+			name:           "root-absolute links relative to the repo",
+			additionalArgs: []string{"--style", "root-absolute"},
+			outputFiles: []file{
+				{
+					name: "docs/foo.md",
+					contents: []string{
+						"# Foo",
+						"Foo mentions [bar](/docs/bar.md) and [the readme](/README.md).",
+					},
+				},
+			},
+		},
+		{
+			name:           "root-absolute links relative to the root",
+			additionalArgs: []string{"--style", "root-absolute", "--root", "docs"},
+			outputFiles: []file{
+				{
+					name: "docs/foo.md",
+					contents: []string{
+						"# Foo",
+						"Foo mentions [bar](/bar.md) and [the readme](../README.md).",
+					},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir := writeFiles(t, []file{
+				{name: ".git/HEAD"},
+				{
+					name: "docs/foo.md",
+					contents: []string{
+						"# Foo",
+						"Foo mentions [bar](bar.md) and [the readme](../README.md).",
+					},
+				},
+				{
+					name:     "docs/bar.md",
+					contents: []string{"# Bar"},
+				},
+				{
+					name:     "README.md",
+					contents: []string{"# Readme"},
+				},
+			}, "markasten-input")
+			args := []string{
+				"links",
+				"normalize",
+				"-i",
+				filepath.Join(repoDir, "docs"),
+			}
+			for _, arg := range tc.additionalArgs {
+				if arg == "docs" {
+					arg = filepath.Join(repoDir, "docs")
+				}
+				args = append(args, arg)
+			}
+			rootCmd := commands.NewRootCmd()
+			rootCmd.SetArgs(args)
+			require.NoError(t, rootCmd.Execute())
+
+			for _, outputFile := range tc.outputFiles {
+				actualOutputBytes, err := os.ReadFile(filepath.Join(repoDir, outputFile.name))
+				require.NoError(t, err)
+				require.Equal(t, strings.Join(outputFile.contents, "\n"), string(actualOutputBytes))
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package commands_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinksCheck(t *testing.T) {
+	for _, tc := range []testCase{
+		linksCheckWithNoBrokenLinks(),
+		linksCheckWithMissingFiles(),
+		linksCheckWithMissingHeadings(),
+		linksCheckWithCaseMismatch(),
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+			outputDir := writeFiles(t, nil, "markasten-output")
+			actualOutputFilePath := filepath.Join(outputDir, tc.outputFiles[0].name)
+
+			rootCmd := commands.NewRootCmd()
+			args := []string{
+				"links",
+				"check",
+				"--debug",
+				"-i",
+				inputDir,
+				"-o",
+				actualOutputFilePath,
+			}
+			args = append(args, tc.additionalArgs...)
+			rootCmd.SetArgs(args)
+			err := rootCmd.Execute()
+
+			expectedOutput := strings.Join(tc.outputFiles[0].contents, "\n")
+			if expectedOutput == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+
+			actualOutputBytes, err := os.ReadFile(actualOutputFilePath)
+			require.NoError(t, err)
+			actualOutput := strings.ReplaceAll(string(actualOutputBytes), inputDir+string(filepath.Separator), "")
+			require.Equal(t, expectedOutput, actualOutput)
+		})
+	}
+}
+
+func linksCheckWithNoBrokenLinks() testCase {
+	return testCase{
+		name: "links check with no broken links",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](./team/bar.md#some-heading), [the top](#foo) and [google](https://google.com).",
+					"It also mentions [[Bar#Some: Heading]] and [an anchor](team/bar.md#anchor).",
+					"![An image](image.png)",
+				},
+			},
+			{
+				name: "image.png",
+			},
+			{
+				name: "team/bar.md",
+				contents: []string{
+					"# Bar",
+					"## Some: Heading",
+					`<a name="anchor"></a>`,
+					"Bar mentions [foo](../foo.md).",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "report.txt",
+			},
+		},
+	}
+}
+
+func linksCheckWithMissingFiles() testCase {
+	return testCase{
+		name: "links check with missing files",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](./bar.md) and [spam](team/spam.md).",
+					"",
+					"```",
+					"[eggs](eggs.md) is in a code block.",
+					"```",
+					"![An image](image.png) and [[Missing Page]].",
+				},
+			},
+			{
+				name: "team/spam.md",
+				contents: []string{
+					"# Spam",
+					"Spam mentions [foo](foo.md).",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "report.txt",
+				contents: []string{
+					"foo.md:2:14: link to ./bar.md, which does not exist",
+					"foo.md:7:1: link to image.png, which does not exist",
+					"foo.md:7:28: wiki link to Missing Page, which does not exist",
+					"team/spam.md:2:15: link to foo.md, which does not exist",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func linksCheckWithMissingHeadings() testCase {
+	return testCase{
+		name: "links check with missing headings",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](bar.md#missing-heading) and [itself](#missing).",
+					"It also mentions [[bar#Missing Heading]] and [[the bar|bar#Heading]].",
+					"And [[the missing heading|bar#Missing Heading]], in the GitHub wiki style.",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"Bar",
+					"===",
+					"## Heading",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "report.txt",
+				contents: []string{
+					"foo.md:2:14: link to bar.md#missing-heading, which does not match a heading in bar.md",
+					"foo.md:2:48: link to #missing, which does not match a heading in foo.md",
+					"foo.md:3:18: link to bar#Missing Heading, which does not match a heading in bar.md",
+					"foo.md:4:5: link to bar#Missing Heading, which does not match a heading in bar.md",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func linksCheckWithCaseMismatch() testCase {
+	return testCase{
+		name: "links check with case mismatch",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"# Foo",
+					"Foo mentions [bar](team/bar.md) and [spam](Team/Spam.md).",
+				},
+			},
+			{
+				name: "team/Bar.md",
+				contents: []string{
+					"# Bar",
+				},
+			},
+			{
+				name: "team/Spam.md",
+				contents: []string{
+					"# Spam",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "report.txt",
+				contents: []string{
+					"foo.md:2:14: link to team/bar.md, which differs in case from team/Bar.md",
+					"foo.md:2:37: link to Team/Spam.md, which differs in case from team/Spam.md",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func TestRootAbsoluteLinks(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		output []string
+		err    bool
+	}{
+		{
+			name: "links check",
+			args: []string{"links", "check"},
+		},
+		{
+			name: "links check relative to the root",
+			args: []string{"links", "check", "--root", "docs"},
+			output: []string{
+				"bar.md:2:14: link to /docs/foo.md#foo, which does not exist",
+				"foo.md:2:14: link to /docs/bar.md, which does not exist",
+				"",
+			},
+			err: true,
+		},
+		{
+			name: "backlinks find",
+			args: []string{"backlinks", "find", "--context-length", "0"},
+			output: []string{
+				"- source: foo.md",
+				"  target: bar.md",
+				"  text: bar",
+				"  line: 2",
+				"- source: bar.md",
+				"  target: foo.md",
+				"  text: foo",
+				"  line: 2",
+				"  section: Foo",
+				"",
+			},
+		},
+		{
+			name:   "orphans",
+			args:   []string{"orphans", "--no-inbound"},
+			output: []string{"# Orphans", "", "## No inbound links", ""},
+		},
+		{
+			name: "graph",
+			args: []string{"graph", "--format", "mermaid"},
+			output: []string{
+				"graph LR",
+				`  n0["Bar"]`,
+				`  n1["Foo"]`,
+				"  n0 --> n1",
+				"  n1 --> n0",
+				"",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir := writeFiles(t, []file{
+				{name: ".git/HEAD"},
+				{
+					name:     "docs/foo.md",
+					contents: []string{"# Foo", "Foo mentions [bar](/docs/bar.md)."},
+				},
+				{
+					name:     "docs/bar.md",
+					contents: []string{"# Bar", "Bar mentions [foo](/docs/foo.md#foo)."},
+				},
+			}, "markasten-input")
+			args := append(tc.args, "-i", filepath.Join(repoDir, "docs"))
+			for i, arg := range args {
+				if arg == "docs" {
+					args[i] = filepath.Join(repoDir, "docs")
+				}
+			}
+			var out bytes.Buffer
+			rootCmd := commands.NewRootCmd()
+			rootCmd.SetOut(&out)
+			rootCmd.SetArgs(args)
+			err := rootCmd.Execute()
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			actualOutput := strings.ReplaceAll(out.String(), filepath.Join(repoDir, "docs")+string(filepath.Separator), "")
+			require.Equal(t, strings.Join(tc.output, "\n"), actualOutput)
+		})
+	}
+}
@@ -0,0 +1,795 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	fenceRegexp          = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+	listItemRegexp       = regexp.MustCompile(`^\s*([-+*]|\d+[.)])\s`)
+	definitionRegexp     = regexp.MustCompile(`^ {0,3}\[((?:[^\[\]\\]|\\.)+)\]:\s*(<[^>\n]*>|\S+)(?:\s+("[^"]*"|'[^']*'|\([^)]*\)))?\s*$`)
+	autolinkRegexp       = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailAutolinkRegexp  = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)*)>`)
+	whitespaceRunsRegexp = regexp.MustCompile(`\s+`)
+	atxHeadingRegexp     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextHeadingRegexp  = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	inlineLinkTextRegexp = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	htmlAnchorRegexp     = regexp.MustCompile(`<a\s[^>]*(?:name|id)\s*=\s*["']([^"']+)["']`)
+	htmlTagRegexp        = regexp.MustCompile(`</?[A-Za-z][^<>]*>|<!--.*?-->`)
+	blockPrefixRegexp    = regexp.MustCompile(`^\s*(?:>\s?)*(?:(?:[-+*]|\d+[.)])\s+)?(?:#{1,6}\s+)?`)
+	hashtagRegexp        = regexp.MustCompile(`#([\p{L}\p{N}_][\p{L}\p{N}_/-]*)`)
+	bareURLRegexp        = regexp.MustCompile(`(?:https?|ftp)://[^\s<>()\[\]]+|www\.[^\s<>()\[\]]+`)
+)
+
+// LinkKind describes the syntax a link was written with.
+type LinkKind int
+
+const (
+	// InlineLink is a link of the form [text](destination "title").
+	InlineLink LinkKind = iota
+	// ReferenceLink is a link of the form [text][ref], [ref][] or [ref],
+	// whose destination is given by a [ref]: destination definition.
+	ReferenceLink
+	// Autolink is a link of the form <https://example.com>.
+	Autolink
+	// WikiLink is a link of the form [[Page Name|label]].
+	WikiLink
+)
+
+// Link is a link found in a Markdown document.
+type Link struct {
+	Kind        LinkKind
+	Image       bool
+	Text        string
+	Destination string
+	Title       string
+	// Line and Column are the 1-based position of the start of the link.
+	Line   int
+	Column int
+
+	// start and end are the byte offsets of the whole link in the document,
+	// and destinationStart and destinationEnd are the byte offsets of its
+	// destination. For reference links, the destination offsets refer to
+	// the link reference definition. For wiki links with a label,
+	// textStart and textEnd are the byte offsets of the label.
+	start            int
+	end              int
+	destinationStart int
+	destinationEnd   int
+	textStart        int
+	textEnd          int
+}
+
+// markdownLine is a line of a Markdown document, along with the block-level
+// context needed to scan it for inline elements.
+type markdownLine struct {
+	number      int
+	offset      int
+	text        string
+	code        bool
+	definition  bool
+	frontmatter bool
+}
+
+// heading is an ATX or setext heading in a Markdown document.
+type heading struct {
+	level int
+	text  string
+	line  int
+	// slug is the anchor GitHub generates for the heading, which can be
+	// linked to using a #slug fragment.
+	slug string
+}
+
+type linkDefinition struct {
+	destination      string
+	title            string
+	destinationStart int
+	destinationEnd   int
+}
+
+// ExtractLinks returns the links in a Markdown document, in the order they
+// appear. Links in code blocks and code spans are ignored.
+func ExtractLinks(contents string) []Link {
+	lines := splitMarkdownLines(contents)
+	definitions := extractLinkDefinitions(lines)
+	var links []Link
+	for _, line := range lines {
+		if line.code || line.definition || line.frontmatter {
+			continue
+		}
+		links = append(links, scanInlineLinks(line, line.text, 0, definitions)...)
+	}
+	return links
+}
+
+// splitMarkdownLines splits a Markdown document into lines, and marks the
+// lines which are part of frontmatter, code blocks or are link reference
+// definitions.
+func splitMarkdownLines(contents string) []markdownLine {
+	var lines []markdownLine
+	offset := 0
+	for n, text := range strings.Split(contents, "\n") {
+		lines = append(lines, markdownLine{
+			number: n + 1,
+			offset: offset,
+			text:   strings.TrimSuffix(text, "\r"),
+		})
+		offset += len(text) + 1
+	}
+
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.text
+	}
+	if format, start, end := frontmatterBounds(texts); format != "" {
+		for i := start; i <= end; i++ {
+			lines[i].frontmatter = true
+		}
+	}
+
+	fence := ""
+	previousBlank := true
+	inList := false
+	for i := range lines {
+		if lines[i].frontmatter {
+			continue
+		}
+		text := lines[i].text
+		blank := strings.TrimSpace(text) == ""
+		if fence != "" {
+			lines[i].code = true
+			if strings.HasPrefix(strings.TrimSpace(text), fence) && strings.Trim(strings.TrimSpace(text), fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if match := fenceRegexp.FindStringSubmatch(text); match != nil {
+			// Backtick fences cannot have backticks in their info string.
+			if match[1][0] != '`' || !strings.Contains(text[len(match[0]):], "`") {
+				fence = match[1]
+				lines[i].code = true
+				continue
+			}
+		}
+		indented := strings.HasPrefix(text, "    ") || strings.HasPrefix(text, "\t")
+		if indented && !blank && !inList && (previousBlank || (i > 0 && lines[i-1].code)) {
+			lines[i].code = true
+			continue
+		}
+		if !blank {
+			if listItemRegexp.MatchString(text) {
+				inList = true
+			} else if !indented {
+				inList = false
+			}
+		}
+		previousBlank = blank
+	}
+	return lines
+}
+
+func extractLinkDefinitions(lines []markdownLine) map[string]linkDefinition {
+	definitions := make(map[string]linkDefinition)
+	for i, line := range lines {
+		if line.code || line.frontmatter {
+			continue
+		}
+		match := definitionRegexp.FindStringSubmatchIndex(line.text)
+		if match == nil {
+			continue
+		}
+		lines[i].definition = true
+		label := normalizeLinkLabel(line.text[match[2]:match[3]])
+		if _, ok := definitions[label]; ok {
+			// The first definition of a label takes precedence.
+			continue
+		}
+		destinationStart, destinationEnd := match[4], match[5]
+		destination := line.text[destinationStart:destinationEnd]
+		if strings.HasPrefix(destination, "<") {
+			destination = destination[1 : len(destination)-1]
+			destinationStart++
+			destinationEnd--
+		}
+		title := ""
+		if match[6] >= 0 {
+			title = line.text[match[6]+1 : match[7]-1]
+		}
+		definitions[label] = linkDefinition{
+			destination:      destination,
+			title:            title,
+			destinationStart: line.offset + destinationStart,
+			destinationEnd:   line.offset + destinationEnd,
+		}
+	}
+	return definitions
+}
+
+// extractHeadings returns the headings in a Markdown document, in the
+// order they appear.
+func extractHeadings(contents string) []heading {
+	lines := splitMarkdownLines(contents)
+	var headings []heading
+	slugCounts := make(map[string]int)
+	add := func(level int, text string, line int) {
+		slug := headingSlug(text)
+		if count := slugCounts[slug]; count > 0 {
+			slugCounts[slug]++
+			slug = fmt.Sprintf("%s-%d", slug, count)
+		} else {
+			slugCounts[slug]++
+		}
+		headings = append(headings, heading{
+			level: level,
+			text:  text,
+			line:  line,
+			slug:  slug,
+		})
+	}
+	for i, line := range lines {
+		if line.code || line.frontmatter {
+			continue
+		}
+		if match := atxHeadingRegexp.FindStringSubmatch(line.text); match != nil {
+			add(len(match[1]), strings.TrimSpace(match[2]), line.number)
+			continue
+		}
+		match := setextHeadingRegexp.FindStringSubmatch(line.text)
+		if match == nil || i == 0 {
+			continue
+		}
+		previous := lines[i-1]
+		if previous.code || previous.frontmatter || previous.definition ||
+			strings.TrimSpace(previous.text) == "" ||
+			atxHeadingRegexp.MatchString(previous.text) ||
+			listItemRegexp.MatchString(previous.text) ||
+			setextHeadingRegexp.MatchString(previous.text) {
+			continue
+		}
+		level := 1
+		if match[1][0] == '-' {
+			level = 2
+		}
+		add(level, strings.TrimSpace(previous.text), previous.number)
+	}
+	return headings
+}
+
+// proseRanges returns the start and end offsets of the parts of a Markdown
+// document which are prose, excluding frontmatter, code, headings, links,
+// URLs and HTML.
+func proseRanges(contents string) [][2]int {
+	lines := splitMarkdownLines(contents)
+	extractLinkDefinitions(lines)
+	headingLines := make(map[int]bool)
+	for _, h := range extractHeadings(contents) {
+		headingLines[h.line] = true
+		// The underline of a setext heading is not prose either.
+		if h.line < len(lines) && setextHeadingRegexp.MatchString(lines[h.line].text) {
+			headingLines[h.line+1] = true
+		}
+	}
+	masksByLine := make(map[int][][2]int)
+	for _, link := range ExtractLinks(contents) {
+		masksByLine[link.Line] = append(masksByLine[link.Line], [2]int{link.start, link.end})
+	}
+
+	var ranges [][2]int
+	for _, line := range lines {
+		if line.code || line.frontmatter || line.definition || headingLines[line.number] {
+			continue
+		}
+		masks := masksByLine[line.number]
+		for _, span := range codeSpans(line.text) {
+			masks = append(masks, [2]int{line.offset + span[0], line.offset + span[1]})
+		}
+		for _, r := range []*regexp.Regexp{htmlTagRegexp, bareURLRegexp} {
+			for _, match := range r.FindAllStringIndex(line.text, -1) {
+				masks = append(masks, [2]int{line.offset + match[0], line.offset + match[1]})
+			}
+		}
+		sort.Slice(masks, func(i, j int) bool {
+			return masks[i][0] < masks[j][0]
+		})
+		start := line.offset
+		for _, mask := range masks {
+			if mask[0] > start {
+				ranges = append(ranges, [2]int{start, mask[0]})
+			}
+			if mask[1] > start {
+				start = mask[1]
+			}
+		}
+		if end := line.offset + len(line.text); end > start {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+	return ranges
+}
+
+// extractHashtags returns the inline #hashtags in the prose of a Markdown
+// document, in the order they first appear. Hashtags must be preceded by
+// whitespace or punctuation, and cannot be entirely numeric.
+func extractHashtags(contents string) []string {
+	var hashtags []string
+	seen := make(map[string]bool)
+	for _, r := range proseRanges(contents) {
+		prose := contents[r[0]:r[1]]
+		for _, match := range hashtagRegexp.FindAllStringSubmatchIndex(prose, -1) {
+			start := r[0] + match[0]
+			if before, _ := utf8.DecodeLastRuneInString(contents[:start]); start > 0 &&
+				(before == '&' || before == '#' || before == '/' || before == '_' ||
+					unicode.IsLetter(before) || unicode.IsNumber(before)) {
+				continue
+			}
+			tag := strings.TrimRight(prose[match[2]:match[3]], "/-")
+			if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsNumber(r) }) < 0 || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			hashtags = append(hashtags, tag)
+		}
+	}
+	return hashtags
+}
+
+// snippet is the text of the paragraph surrounding a link, with whitespace
+// collapsed and links replaced by their text. start and end are the rune
+// offsets of the text of the link itself.
+type snippet struct {
+	text  string
+	start int
+	end   int
+}
+
+// linkSnippet returns the snippet of the paragraph, list item or heading
+// containing a link, given the lines of the document and all of its links.
+func linkSnippet(lines []markdownLine, links []Link, link Link) snippet {
+	inParagraph := func(line markdownLine) bool {
+		return !line.code && !line.frontmatter && !line.definition && strings.TrimSpace(line.text) != ""
+	}
+	startsBlock := func(line markdownLine) bool {
+		return listItemRegexp.MatchString(line.text) || atxHeadingRegexp.MatchString(line.text)
+	}
+	first, last := link.Line-1, link.Line-1
+	for first > 0 && inParagraph(lines[first-1]) && !startsBlock(lines[first]) &&
+		!atxHeadingRegexp.MatchString(lines[first-1].text) {
+		first--
+	}
+	for last+1 < len(lines) && inParagraph(lines[last+1]) && !startsBlock(lines[last+1]) &&
+		!setextHeadingRegexp.MatchString(lines[last+1].text) &&
+		!atxHeadingRegexp.MatchString(lines[last].text) {
+		last++
+	}
+
+	var text []rune
+	write := func(s string) {
+		for _, r := range s {
+			if unicode.IsSpace(r) {
+				if len(text) == 0 || text[len(text)-1] == ' ' {
+					continue
+				}
+				r = ' '
+			}
+			text = append(text, r)
+		}
+	}
+	result := snippet{}
+	for _, line := range lines[first : last+1] {
+		position := line.offset + len(blockPrefixRegexp.FindString(line.text))
+		for _, l := range links {
+			// Images nested in links are skipped, along with the link text
+			// containing them.
+			if l.Line != line.number || l.start < position {
+				continue
+			}
+			write(line.text[position-line.offset : l.start-line.offset])
+			if l.start == link.start {
+				result.start = len(text)
+			}
+			write(l.Text)
+			if l.start == link.start {
+				result.end = len(text)
+			}
+			position = l.end
+		}
+		write(line.text[position-line.offset:])
+		write(" ")
+	}
+	result.text = strings.TrimRightFunc(string(text), unicode.IsSpace)
+	if result.end > len([]rune(result.text)) {
+		result.end = len([]rune(result.text))
+	}
+	return result
+}
+
+// trim returns the text of the snippet, trimmed to at most length runes
+// around the link at word boundaries, with an ellipsis marking any text
+// which has been removed.
+func (s snippet) trim(length int) string {
+	text := []rune(s.text)
+	if len(text) <= length {
+		return s.text
+	}
+	start := (s.start+s.end)/2 - length/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + length
+	if end > len(text) {
+		end = len(text)
+		start = end - length
+	}
+	if start > 0 {
+		for i := start; i < s.start && i < end; i++ {
+			if text[i] == ' ' {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if end < len(text) {
+		for i := end; i > s.end && i > start; i-- {
+			if text[i-1] == ' ' {
+				end = i - 1
+				break
+			}
+		}
+	}
+	trimmed := string(text[start:end])
+	if start > 0 {
+		trimmed = "…" + trimmed
+	}
+	if end < len(text) {
+		trimmed += "…"
+	}
+	return trimmed
+}
+
+// headingSlug returns the anchor GitHub generates for a heading, by
+// lowercasing it, removing punctuation and replacing spaces with hyphens.
+func headingSlug(text string) string {
+	text = inlineLinkTextRegexp.ReplaceAllString(text, "$1")
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ':
+			slug.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			slug.WriteRune(r)
+		}
+	}
+	return slug.String()
+}
+
+// extractAnchors returns the anchors which can be linked to in a Markdown
+// document, from the slugs of its headings and any HTML anchors it contains,
+// along with the heading each refers to. For HTML anchors, the heading is
+// named after the anchor.
+func extractAnchors(contents string) map[string]heading {
+	anchors := make(map[string]heading)
+	for _, h := range extractHeadings(contents) {
+		anchors[h.slug] = h
+	}
+	for _, match := range htmlAnchorRegexp.FindAllStringSubmatchIndex(contents, -1) {
+		name := contents[match[2]:match[3]]
+		slug := strings.ToLower(name)
+		if _, ok := anchors[slug]; ok {
+			continue
+		}
+		anchors[slug] = heading{
+			text: name,
+			line: strings.Count(contents[:match[0]], "\n") + 1,
+			slug: slug,
+		}
+	}
+	return anchors
+}
+
+// normalizeLinkLabel normalizes a link label so that labels which differ
+// only in case or whitespace match.
+func normalizeLinkLabel(label string) string {
+	return strings.ToLower(whitespaceRunsRegexp.ReplaceAllString(strings.TrimSpace(label), " "))
+}
+
+// scanInlineLinks returns the links in text, which begins at the given
+// offset into the line.
+func scanInlineLinks(line markdownLine, text string, offset int, definitions map[string]linkDefinition) []Link {
+	var links []Link
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '\\':
+			i += 2
+		case '`':
+			i = skipCodeSpan(text, i)
+		case '<':
+			link, end, ok := scanAutolink(text, i)
+			if !ok {
+				i++
+				continue
+			}
+			links = append(links, positionLink(link, line, offset, i, end))
+			i = end
+		case '[', '!':
+			image := text[i] == '!'
+			bracket := i
+			if image {
+				if i+1 >= len(text) || text[i+1] != '[' {
+					i++
+					continue
+				}
+				bracket++
+			}
+			if !image && strings.HasPrefix(text[i:], "[[") {
+				if link, end, ok := scanWikiLink(text, i); ok {
+					links = append(links, positionLink(link, line, offset, i, end))
+					i = end
+					continue
+				}
+			}
+			closing := matchingBracket(text, bracket)
+			if closing < 0 {
+				i = bracket + 1
+				continue
+			}
+			link, end, ok := scanLinkTail(text, closing+1, text[bracket+1:closing], definitions)
+			if !ok {
+				i = bracket + 1
+				continue
+			}
+			link.Image = image
+			link.Text = text[bracket+1 : closing]
+			links = append(links, positionLink(link, line, offset, i, end))
+			// Links cannot contain other links, but they can contain images.
+			for _, nested := range scanInlineLinks(line, link.Text, offset+bracket+1, definitions) {
+				if nested.Image {
+					links = append(links, nested)
+				}
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return links
+}
+
+// positionLink sets the position of a link found between start and end in
+// text which begins at the given offset into the line. Apart from those of
+// reference links, the destination offsets of the link are converted from
+// offsets into the text to offsets into the document.
+func positionLink(link Link, line markdownLine, offset int, start int, end int) Link {
+	link.Line = line.number
+	link.Column = offset + start + 1
+	link.start = line.offset + offset + start
+	link.end = line.offset + offset + end
+	if link.Kind != ReferenceLink {
+		link.destinationStart += line.offset + offset
+		link.destinationEnd += line.offset + offset
+	}
+	if link.textStart != link.textEnd {
+		link.textStart += line.offset + offset
+		link.textEnd += line.offset + offset
+	}
+	return link
+}
+
+// skipCodeSpan returns the index after the code span starting at i, or
+// after the run of backticks at i if it does not start a code span.
+func skipCodeSpan(text string, i int) int {
+	run := backtickRun(text, i)
+	for j := i + run; j < len(text); {
+		next := strings.Index(text[j:], strings.Repeat("`", run))
+		if next < 0 {
+			break
+		}
+		// A longer run of backticks does not close the code span.
+		if closing := backtickRun(text, j+next); closing != run {
+			j += next + closing
+			continue
+		}
+		return j + next + run
+	}
+	return i + run
+}
+
+func backtickRun(text string, i int) int {
+	run := 0
+	for i+run < len(text) && text[i+run] == '`' {
+		run++
+	}
+	return run
+}
+
+// codeSpans returns the start and end indexes of the code spans in text.
+func codeSpans(text string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '\\':
+			i += 2
+		case '`':
+			end := skipCodeSpan(text, i)
+			if end != i+backtickRun(text, i) {
+				spans = append(spans, [2]int{i, end})
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return spans
+}
+
+// matchingBracket returns the index of the ] matching the [ at i, or -1 if
+// there is none.
+func matchingBracket(text string, i int) int {
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '`':
+			j = skipCodeSpan(text, j) - 1
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// scanLinkTail parses the part of a link following its text, which starts
+// at i, and returns the link and the index after it.
+func scanLinkTail(text string, i int, label string, definitions map[string]linkDefinition) (Link, int, bool) {
+	if i < len(text) && text[i] == '(' {
+		return scanInlineDestination(text, i)
+	}
+	end := i
+	if i < len(text) && text[i] == '[' {
+		closing := strings.IndexByte(text[i:], ']')
+		if closing < 0 {
+			return Link{}, 0, false
+		}
+		if closing > 1 {
+			label = text[i+1 : i+closing]
+		}
+		end = i + closing + 1
+	}
+	definition, ok := definitions[normalizeLinkLabel(label)]
+	if !ok {
+		return Link{}, 0, false
+	}
+	return Link{
+		Kind:             ReferenceLink,
+		Destination:      definition.destination,
+		Title:            definition.title,
+		destinationStart: definition.destinationStart,
+		destinationEnd:   definition.destinationEnd,
+	}, end, true
+}
+
+// scanInlineDestination parses the destination and optional title of an
+// inline link, in parentheses starting at i.
+func scanInlineDestination(text string, i int) (Link, int, bool) {
+	j := skipSpaces(text, i+1)
+	link := Link{Kind: InlineLink}
+	if j < len(text) && text[j] == '<' {
+		closing := strings.IndexAny(text[j+1:], "<>")
+		if closing < 0 || text[j+1+closing] != '>' {
+			return Link{}, 0, false
+		}
+		link.destinationStart, link.destinationEnd = j+1, j+1+closing
+		j += closing + 2
+	} else {
+		start := j
+		depth := 0
+	destination:
+		for ; j < len(text); j++ {
+			switch c := text[j]; {
+			case c == '\\':
+				j++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break destination
+				}
+				depth--
+			case c == ' ' || c == '\t' || c < 0x20:
+				break destination
+			}
+		}
+		if j > len(text) {
+			j = len(text)
+		}
+		link.destinationStart, link.destinationEnd = start, j
+	}
+	link.Destination = text[link.destinationStart:link.destinationEnd]
+
+	j = skipSpaces(text, j)
+	if j < len(text) && strings.IndexByte(`"'(`, text[j]) >= 0 {
+		closer := text[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		closing := strings.IndexByte(text[j+1:], closer)
+		if closing < 0 {
+			return Link{}, 0, false
+		}
+		link.Title = text[j+1 : j+1+closing]
+		j = skipSpaces(text, j+closing+2)
+	}
+	if j >= len(text) || text[j] != ')' {
+		return Link{}, 0, false
+	}
+	return link, j + 1, true
+}
+
+func skipSpaces(text string, i int) int {
+	for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func scanAutolink(text string, i int) (Link, int, bool) {
+	for _, r := range []*regexp.Regexp{autolinkRegexp, emailAutolinkRegexp} {
+		match := r.FindStringSubmatchIndex(text[i:])
+		if match == nil {
+			continue
+		}
+		destination := text[i+match[2] : i+match[3]]
+		if r == emailAutolinkRegexp {
+			destination = "mailto:" + destination
+		}
+		return Link{
+			Kind:             Autolink,
+			Text:             text[i+match[2] : i+match[3]],
+			Destination:      destination,
+			destinationStart: i + match[2],
+			destinationEnd:   i + match[3],
+		}, i + match[1], true
+	}
+	return Link{}, 0, false
+}
+
+// scanWikiLink parses a wiki link of the form [[Page Name#Heading|label]]
+// starting at i. The destination of the link is the page name, including
+// any heading, and its text is the label if there is one.
+func scanWikiLink(text string, i int) (Link, int, bool) {
+	closing := strings.Index(text[i+2:], "]]")
+	if closing < 0 {
+		return Link{}, 0, false
+	}
+	inner := text[i+2 : i+2+closing]
+	end := i + 2 + closing + 2
+	if inner == "" || strings.ContainsAny(inner, "[]") || (end < len(text) && text[end] == '(') {
+		return Link{}, 0, false
+	}
+	destination, label, hasLabel := strings.Cut(inner, "|")
+	link := Link{
+		Kind:             WikiLink,
+		Text:             strings.TrimSpace(label),
+		Destination:      strings.TrimSpace(destination),
+		destinationStart: i + 2,
+		destinationEnd:   i + 2 + len(destination),
+	}
+	if hasLabel {
+		link.textStart = link.destinationEnd + 1
+		link.textEnd = end - 2
+	} else {
+		link.Text = link.Destination
+	}
+	return link, end, true
+}
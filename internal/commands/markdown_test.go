@@ -0,0 +1,254 @@
+package commands_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+type extractedLink struct {
+	kind        commands.LinkKind
+	image       bool
+	text        string
+	destination string
+	title       string
+	line        int
+	column      int
+}
+
+type extractLinksTestCase struct {
+	name  string
+	input []string
+	links []extractedLink
+}
+
+func TestExtractLinks(t *testing.T) {
+	for _, tc := range []extractLinksTestCase{
+		{
+			name:  "no links",
+			input: []string{"foo bar spam"},
+		},
+		{
+			name:  "inline link",
+			input: []string{"foo [bar](spam)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "bar", destination: "spam", line: 1, column: 5},
+			},
+		},
+		{
+			name:  "two inline links on one line",
+			input: []string{"[foo](foo.md) and [bar](bar.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo", destination: "foo.md", line: 1, column: 1},
+				{kind: commands.InlineLink, text: "bar", destination: "bar.md", line: 1, column: 19},
+			},
+		},
+		{
+			name: "links on multiple lines",
+			input: []string{
+				"# Foo",
+				"",
+				"Foo mentions [bar](bar.md).",
+				"  - And [spam](spam.md).",
+			},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "bar", destination: "bar.md", line: 3, column: 14},
+				{kind: commands.InlineLink, text: "spam", destination: "spam.md", line: 4, column: 9},
+			},
+		},
+		{
+			name:  "link with a title",
+			input: []string{`[foo](foo.md "The foo") [bar](bar.md 'The bar') [spam](spam.md (The spam))`},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo", destination: "foo.md", title: "The foo", line: 1, column: 1},
+				{kind: commands.InlineLink, text: "bar", destination: "bar.md", title: "The bar", line: 1, column: 25},
+				{kind: commands.InlineLink, text: "spam", destination: "spam.md", title: "The spam", line: 1, column: 49},
+			},
+		},
+		{
+			name:  "link with a destination in angle brackets",
+			input: []string{"[foo](<my foo.md>)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo", destination: "my foo.md", line: 1, column: 1},
+			},
+		},
+		{
+			name:  "link with parentheses in its destination",
+			input: []string{"[foo](foo_(bar).md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo", destination: "foo_(bar).md", line: 1, column: 1},
+			},
+		},
+		{
+			name:  "link with nested brackets in its text",
+			input: []string{"[foo [bar] spam](foo.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo [bar] spam", destination: "foo.md", line: 1, column: 1},
+			},
+		},
+		{
+			name:  "link with a code span in its text",
+			input: []string{"[`foo]`](foo.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "`foo]`", destination: "foo.md", line: 1, column: 1},
+			},
+		},
+		{
+			name:  "escaped brackets",
+			input: []string{`\[foo](foo.md) and [bar\]](bar.md)`},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: `bar\]`, destination: "bar.md", line: 1, column: 20},
+			},
+		},
+		{
+			name:  "brackets which are not links",
+			input: []string{"- [ ] foo", "- [x] bar (spam)", "[foo] [bar]"},
+		},
+		{
+			name:  "unclosed link",
+			input: []string{"[foo](foo.md and [bar](bar.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "bar", destination: "bar.md", line: 1, column: 18},
+			},
+		},
+		{
+			name:  "link in a code span",
+			input: []string{"`[foo](foo.md)` and ``[bar](`bar.md`)`` but [spam](spam.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "spam", destination: "spam.md", line: 1, column: 45},
+			},
+		},
+		{
+			name:  "unclosed code span",
+			input: []string{"`` [foo](foo.md) `"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo", destination: "foo.md", line: 1, column: 4},
+			},
+		},
+		{
+			name: "link in a fenced code block",
+			input: []string{
+				"```markdown",
+				"[foo](foo.md)",
+				"```",
+				"~~~~",
+				"[bar](bar.md)",
+				"```",
+				"~~~~",
+				"[spam](spam.md)",
+			},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "spam", destination: "spam.md", line: 8, column: 1},
+			},
+		},
+		{
+			name: "link in an unclosed fenced code block",
+			input: []string{
+				"```",
+				"[foo](foo.md)",
+			},
+		},
+		{
+			name: "link in an indented code block",
+			input: []string{
+				"Some code:",
+				"",
+				"    [foo](foo.md)",
+				"",
+				"    [bar](bar.md)",
+				"Not [spam](spam.md)",
+			},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "spam", destination: "spam.md", line: 6, column: 5},
+			},
+		},
+		{
+			name: "link in an indented list item",
+			input: []string{
+				"- foo",
+				"",
+				"    - [bar](bar.md)",
+			},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "bar", destination: "bar.md", line: 3, column: 7},
+			},
+		},
+		{
+			name: "reference links",
+			input: []string{
+				"[foo][1], [Bar][] and [spam].",
+				"[eggs][missing]",
+				"",
+				"[1]: foo.md",
+				"[bar]: <my bar.md> \"The bar\"",
+				"[SPAM]: spam.md",
+				"[1]: ignored.md",
+			},
+			links: []extractedLink{
+				{kind: commands.ReferenceLink, text: "foo", destination: "foo.md", line: 1, column: 1},
+				{kind: commands.ReferenceLink, text: "Bar", destination: "my bar.md", title: "The bar", line: 1, column: 11},
+				{kind: commands.ReferenceLink, text: "spam", destination: "spam.md", line: 1, column: 23},
+			},
+		},
+		{
+			name:  "autolinks",
+			input: []string{"<https://example.com/foo?bar=spam> and <foo@example.com>, not <foo bar>"},
+			links: []extractedLink{
+				{kind: commands.Autolink, text: "https://example.com/foo?bar=spam", destination: "https://example.com/foo?bar=spam", line: 1, column: 1},
+				{kind: commands.Autolink, text: "foo@example.com", destination: "mailto:foo@example.com", line: 1, column: 40},
+			},
+		},
+		{
+			name:  "images",
+			input: []string{"![foo](foo.png) and [![bar](bar.png)](bar.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, image: true, text: "foo", destination: "foo.png", line: 1, column: 1},
+				{kind: commands.InlineLink, text: "![bar](bar.png)", destination: "bar.md", line: 1, column: 21},
+				{kind: commands.InlineLink, image: true, text: "bar", destination: "bar.png", line: 1, column: 22},
+			},
+		},
+		{
+			name:  "wiki links",
+			input: []string{"[[foo]], [[Bar Spam#Eggs]] and [[spam|the spam]]"},
+			links: []extractedLink{
+				{kind: commands.WikiLink, text: "foo", destination: "foo", line: 1, column: 1},
+				{kind: commands.WikiLink, text: "Bar Spam#Eggs", destination: "Bar Spam#Eggs", line: 1, column: 10},
+				{kind: commands.WikiLink, text: "the spam", destination: "spam", line: 1, column: 32},
+			},
+		},
+		{
+			name:  "link with brackets in its text",
+			input: []string{"[[foo]](foo.md)"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "[foo]", destination: "foo.md", line: 1, column: 1},
+			},
+		},
+		{
+			name:  "crlf line endings",
+			input: []string{"[foo](foo.md)\r", "[bar](bar.md)\r"},
+			links: []extractedLink{
+				{kind: commands.InlineLink, text: "foo", destination: "foo.md", line: 1, column: 1},
+				{kind: commands.InlineLink, text: "bar", destination: "bar.md", line: 2, column: 1},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var links []extractedLink
+			for _, link := range commands.ExtractLinks(strings.Join(tc.input, "\n")) {
+				links = append(links, extractedLink{
+					kind:        link.Kind,
+					image:       link.Image,
+					text:        link.Text,
+					destination: link.Destination,
+					title:       link.Title,
+					line:        link.Line,
+					column:      link.Column,
+				})
+			}
+			require.Equal(t, tc.links, links)
+		})
+	}
+}
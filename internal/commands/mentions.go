@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mentionsInputPath    *string
+	mentionsOutputPath   *string
+	mentionsFix          *bool
+	mentionsMinLength    *int
+	mentionsDebugEnabled *bool
+)
+
+// mentionName is a name which a note can be mentioned by.
+type mentionName struct {
+	target string
+	text   string
+	regexp *regexp.Regexp
+	// fixable is true if mentions of the name can be linked automatically
+	// when they match it exactly, which is the case for titles other than
+	// the file name, and names of more than one word. A file name of a
+	// single word is often a common word, such as "about".
+	fixable bool
+}
+
+// mention is a plain-text mention of a note, which is not a link.
+type mention struct {
+	fileName string
+	target   string
+	text     string
+	start    int
+	end      int
+	line     int
+	column   int
+	// fixable is true if the mention matches the case of a title or a
+	// file name of more than one word, and can be linked by --fix.
+	fixable bool
+}
+
+func newMentionsCommand() *cobra.Command {
+	mentionsCommand := &cobra.Command{
+		Use:   "mentions",
+		Short: "Find plain-text mentions of notes which are not links",
+		RunE:  mentionsRunFn,
+	}
+	mentionsInputPath = mentionsCommand.Flags().StringP("input", "i", "", "The location of the input files")
+	mentionsOutputPath = mentionsCommand.Flags().StringP("output", "o", "", "The location of an optional file to write the report to, instead of stdout")
+	mentionsFix = mentionsCommand.Flags().Bool("fix", false, "If set, unlinked mentions which match the case of a note's title, or of a file name of more than one word, will be rewritten as relative links to the note they mention")
+	mentionsMinLength = mentionsCommand.Flags().Int("min-length", 3, "The minimum length of a title or file name for mentions of it to be found")
+	mentionsDebugEnabled = mentionsCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	debugEnabled = mentionsDebugEnabled
+	return mentionsCommand
+}
+
+func mentionsRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = mentionsDebugEnabled
+	debug("mentions called with -i %s and -o %s\n", *mentionsInputPath, *mentionsOutputPath)
+	inputDirEntries, err := newFullDirEntryList(*mentionsInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *mentionsInputPath)
+	if err != nil {
+		panic(err)
+	}
+
+	var notes []string
+	contentsByFile := make(map[string]string)
+	var names []mentionName
+	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		notes = append(notes, dirEntry.Name())
+		contentsByFile[dirEntry.Name()] = string(fileBytes)
+		_, title := scrapeTagsAndTitle(dirEntry.Name(), fileBytes)
+		names = append(names, newMentionNames(dirEntry.Name(), title, *mentionsMinLength)...)
+	}
+
+	var output io.Writer = cmd.OutOrStdout()
+	if *mentionsOutputPath != "" {
+		outputFile, err := os.Create(*mentionsOutputPath)
+		if err != nil {
+			panic(err)
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+	for _, note := range notes {
+		contents := contentsByFile[note]
+		mentions := findMentions(note, contents, names)
+		for _, m := range mentions {
+			writeOrPanic(output, fmt.Sprintf(
+				"%s:%d:%d: unlinked mention of %q, which could link to %s\n",
+				m.fileName,
+				m.line,
+				m.column,
+				m.text,
+				mentionLinkDestination(m),
+			))
+		}
+		if !*mentionsFix || len(mentions) == 0 {
+			continue
+		}
+		debug("linking %d mentions in %s", len(mentions), note)
+		for i := len(mentions) - 1; i >= 0; i-- {
+			m := mentions[i]
+			if !m.fixable {
+				continue
+			}
+			link := fmt.Sprintf("[%s](%s)", m.text, mentionLinkDestination(m))
+			contents = contents[:m.start] + link + contents[m.end:]
+		}
+		if err := os.WriteFile(note, []byte(contents), 0644); err != nil {
+			panic(err)
+		}
+	}
+	return nil
+}
+
+// newMentionNames returns the names a note can be mentioned by: its title,
+// its file name without an extension and, if the file name contains hyphens
+// or underscores, its file name with them replaced by spaces. Names are
+// matched case-insensitively.
+func newMentionNames(fileName string, title string, minLength int) []mentionName {
+	base := makeWikiLink(filepath.Base(fileName))
+	candidates := []string{title, base, strings.NewReplacer("-", " ", "_", " ").Replace(base)}
+	var names []mentionName
+	seen := make(map[string]bool)
+	for i, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		key := strings.ToLower(candidate)
+		if utf8.RuneCountInString(candidate) < minLength || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, mentionName{
+			target:  fileName,
+			text:    candidate,
+			regexp:  regexp.MustCompile("(?i)" + regexp.QuoteMeta(candidate)),
+			fixable: (i == 0 && candidate != base) || len(strings.Fields(candidate)) > 1,
+		})
+	}
+	return names
+}
+
+// findMentions returns the unlinked mentions of other notes in the prose of
+// the given file, in the order they appear. Where mentions overlap, the
+// longest is preferred.
+func findMentions(fileName string, contents string, names []mentionName) []mention {
+	var candidates []mention
+	for _, r := range proseRanges(contents) {
+		prose := contents[r[0]:r[1]]
+		for _, name := range names {
+			if name.target == fileName {
+				continue
+			}
+			for _, match := range name.regexp.FindAllStringIndex(prose, -1) {
+				if !isWordBoundary(prose, match[0], match[1]) {
+					continue
+				}
+				text := prose[match[0]:match[1]]
+				candidates = append(candidates, mention{
+					fileName: fileName,
+					target:   name.target,
+					text:     text,
+					start:    r[0] + match[0],
+					end:      r[0] + match[1],
+					fixable:  name.fixable && text == name.text,
+				})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].start != candidates[j].start {
+			return candidates[i].start < candidates[j].start
+		}
+		return candidates[i].end > candidates[j].end
+	})
+
+	var mentions []mention
+	end := 0
+	for _, candidate := range candidates {
+		if candidate.start < end {
+			continue
+		}
+		end = candidate.end
+		lineStart := strings.LastIndex(contents[:candidate.start], "\n") + 1
+		candidate.line = strings.Count(contents[:candidate.start], "\n") + 1
+		candidate.column = candidate.start - lineStart + 1
+		mentions = append(mentions, candidate)
+	}
+	return mentions
+}
+
+// isWordBoundary returns whether the text between start and end is a whole
+// word, or words.
+func isWordBoundary(text string, start int, end int) bool {
+	isWordRune := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+	}
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+// mentionLinkDestination returns the destination of a link from the file
+// containing a mention to the note it mentions.
+func mentionLinkDestination(m mention) string {
+	destination := filepath.ToSlash(relativeTo(m.target, m.fileName))
+	if strings.ContainsAny(destination, " ()<>") {
+		return fmt.Sprintf("<%s>", destination)
+	}
+	return destination
+}
@@ -0,0 +1,147 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMentions(t *testing.T) {
+	for _, tc := range []testCase{
+		basicMentions(),
+		mentionsWithFix(),
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+
+			rootCmd := commands.NewRootCmd()
+			args := []string{
+				"mentions",
+				"--debug",
+				"-i",
+				inputDir,
+				"-o",
+				filepath.Join(inputDir, "mentions.txt"),
+			}
+			args = append(args, tc.additionalArgs...)
+			rootCmd.SetArgs(args)
+			require.NoError(t, rootCmd.Execute())
+
+			for _, outputFile := range tc.outputFiles {
+				actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, outputFile.name))
+				require.NoError(t, err)
+				actualOutput := strings.ReplaceAll(string(actualOutputBytes), inputDir+string(filepath.Separator), "")
+				require.Equal(t, strings.Join(outputFile.contents, "\n"), actualOutput)
+			}
+		})
+	}
+}
+
+func mentionsInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- foo",
+				"---",
+				"# Team Foo",
+				"Team Foo works with the bar team, and on spam eggs.",
+			},
+		},
+		{
+			name: "bar.md",
+			contents: []string{
+				"# Bar",
+				"Bar is not mentioned in barn, but is mentioned by [team foo](foo.md).",
+			},
+		},
+		{
+			name: "team/spam-eggs.md",
+			contents: []string{
+				"## About team foo",
+				"",
+				"Working with `team foo` and:",
+				"",
+				"```",
+				"team foo",
+				"```",
+				"",
+				"- Team foo",
+				"- BAR",
+				"- Bar",
+			},
+		},
+	}
+}
+
+func basicMentions() testCase {
+	return testCase{
+		name:       "basic mentions",
+		inputFiles: mentionsInputFiles(),
+		outputFiles: []file{
+			{
+				name: "mentions.txt",
+				contents: []string{
+					"foo.md:6:25: unlinked mention of \"bar\", which could link to bar.md",
+					"foo.md:6:42: unlinked mention of \"spam eggs\", which could link to team/spam-eggs.md",
+					"team/spam-eggs.md:9:3: unlinked mention of \"Team foo\", which could link to ../foo.md",
+					"team/spam-eggs.md:10:3: unlinked mention of \"BAR\", which could link to ../bar.md",
+					"team/spam-eggs.md:11:3: unlinked mention of \"Bar\", which could link to ../bar.md",
+					"",
+				},
+			},
+			{
+				name:     "foo.md",
+				contents: mentionsInputFiles()[0].contents,
+			},
+		},
+	}
+}
+
+func mentionsWithFix() testCase {
+	return testCase{
+		name:           "mentions with fix",
+		additionalArgs: []string{"--fix"},
+		inputFiles:     mentionsInputFiles(),
+		outputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- foo",
+					"---",
+					"# Team Foo",
+					"Team Foo works with the bar team, and on [spam eggs](team/spam-eggs.md).",
+				},
+			},
+			{
+				name:     "bar.md",
+				contents: mentionsInputFiles()[1].contents,
+			},
+			{
+				name: "team/spam-eggs.md",
+				contents: []string{
+					"## About team foo",
+					"",
+					"Working with `team foo` and:",
+					"",
+					"```",
+					"team foo",
+					"```",
+					"",
+					"- Team foo",
+					"- BAR",
+					"- [Bar](../bar.md)",
+				},
+			},
+		},
+	}
+}
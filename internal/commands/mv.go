@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mvInputPath    *string
+	mvRoot         *string
+	mvDryRun       *bool
+	mvDebugEnabled *bool
+)
+
+// linkEdit is a change to the destination of a link in a file.
+type linkEdit struct {
+	fileName       string
+	line           int
+	start          int
+	end            int
+	oldDestination string
+	newDestination string
+}
+
+func newMvCommand() *cobra.Command {
+	mvCommand := &cobra.Command{
+		Use:   "mv <source> <destination>",
+		Short: "Move a note, and rewrite the links to and from it",
+		Args:  cobra.ExactArgs(2),
+		RunE:  mvRunFn,
+	}
+	mvInputPath = mvCommand.Flags().StringP("input", "i", ".", "The location of the input files containing links to rewrite")
+	mvRoot = mvCommand.Flags().String("root", "", rootFlagUsage)
+	mvDryRun = mvCommand.Flags().Bool("dry-run", false, "If set, the planned changes will be printed instead of made")
+	mvDebugEnabled = mvCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	debugEnabled = mvDebugEnabled
+	return mvCommand
+}
+
+func mvRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = mvDebugEnabled
+	source, destination := filepath.Clean(args[0]), filepath.Clean(args[1])
+	debug("mv called with -i %s, moving %s to %s\n", *mvInputPath, source, destination)
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if sourceInfo.IsDir() {
+		return fmt.Errorf("%s is a directory, only files can be moved", source)
+	}
+	if info, err := os.Stat(destination); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s already exists", destination)
+		}
+		destination = filepath.Join(destination, filepath.Base(source))
+		if _, err := os.Stat(destination); err == nil {
+			return fmt.Errorf("%s already exists", destination)
+		}
+	}
+
+	inputDirEntries, err := newFullDirEntryList(*mvInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *mvInputPath)
+	if err != nil {
+		panic(err)
+	}
+	contentsByFile := make(map[string][]byte)
+	for _, dirEntry := range searchResults {
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		contentsByFile[dirEntry.Name()] = fileBytes
+	}
+	pages := newWikiPages(searchResults, *mvInputPath, contentsByFile)
+	root := linkRoot(*mvRoot, *mvInputPath)
+
+	var edits []linkEdit
+	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		edits = append(edits, planLinkEdits(
+			dirEntry.Name(),
+			string(contentsByFile[dirEntry.Name()]),
+			*mvInputPath,
+			root,
+			source,
+			destination,
+			pages,
+		)...)
+	}
+
+	if *mvDryRun {
+		output := cmd.OutOrStdout()
+		for _, edit := range edits {
+			fmt.Fprintf(output, "%s:%d: %s -> %s\n", edit.fileName, edit.line, edit.oldDestination, edit.newDestination)
+		}
+		fmt.Fprintf(output, "rename %s -> %s\n", source, destination)
+		return nil
+	}
+
+	editsByFile := make(map[string][]linkEdit)
+	for _, edit := range edits {
+		editsByFile[edit.fileName] = append(editsByFile[edit.fileName], edit)
+	}
+	for fileName, fileEdits := range editsByFile {
+		contents, ok := contentsByFile[fileName]
+		if !ok {
+			panic(fmt.Sprintf("no contents read for %s", fileName))
+		}
+		debug("rewriting %d links in %s", len(fileEdits), fileName)
+		if err := os.WriteFile(fileName, []byte(applyLinkEdits(string(contents), fileEdits)), 0644); err != nil {
+			panic(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		panic(err)
+	}
+	if err := os.Rename(source, destination); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// planLinkEdits returns the edits needed to the links in a file when source
+// is moved to destination. Links to the source are rewritten to refer to the
+// destination and, if the file is the source itself, its relative links are
+// rewritten to account for its new directory. Wiki links are relative to
+// the input directory, and root-absolute links to the root.
+func planLinkEdits(
+	fileName string,
+	contents string,
+	inputPath string,
+	root string,
+	source string,
+	destination string,
+	pages wikiPages,
+) []linkEdit {
+	moved := absPath(fileName) == absPath(source)
+	newFileName := fileName
+	if moved {
+		newFileName = destination
+	}
+
+	var edits []linkEdit
+	edited := make(map[int]bool)
+	for _, link := range ExtractLinks(contents) {
+		if edited[link.destinationStart] {
+			// Reference links share the destination of their definition.
+			continue
+		}
+		var newDestination string
+		if link.Kind == WikiLink {
+			link = pages.orient(link)
+			// Wiki links which refer to the source by its title are
+			// unaffected by it moving.
+			target, ok := pages.byName[wikiPageKey(wikiPageName(link.Destination))]
+			if !ok || absPath(target) != absPath(source) {
+				continue
+			}
+			newDestination = rewriteWikiDestination(link.Destination, inputPath, destination)
+		} else {
+			target, ok := resolveLink(root, fileName, link.Destination)
+			if !ok {
+				continue
+			}
+			targetMoved := absPath(target) == absPath(source)
+			if !targetMoved && (!moved || strings.HasPrefix(link.Destination, "/")) {
+				continue
+			}
+			if targetMoved {
+				target = destination
+			}
+			newDestination = rewriteLinkDestination(link.Destination, root, newFileName, target)
+		}
+		if newDestination == link.Destination {
+			continue
+		}
+		edited[link.destinationStart] = true
+		edits = append(edits, linkEdit{
+			fileName:       fileName,
+			line:           link.Line,
+			start:          link.destinationStart,
+			end:            link.destinationEnd,
+			oldDestination: link.Destination,
+			newDestination: newDestination,
+		})
+	}
+	return edits
+}
+
+// rewriteLinkDestination returns the destination of a link from fileName to
+// target, in the same style as the original destination. Root-absolute
+// destinations remain root-absolute, a leading ./ is preserved, as are any
+// query string and fragment.
+func rewriteLinkDestination(original string, root string, fileName string, target string) string {
+	suffix := ""
+	if i := strings.IndexAny(original, "?#"); i >= 0 {
+		suffix = original[i:]
+	}
+	newPath := linkPath(root, fileName, target, strings.HasPrefix(original, "/"))
+	if strings.HasPrefix(original, "./") && !strings.HasPrefix(newPath, "../") {
+		newPath = "./" + newPath
+	}
+	if strings.Contains(original, "%") || strings.Contains(newPath, " ") {
+		newPath = (&url.URL{Path: newPath}).EscapedPath()
+	}
+	return newPath + suffix
+}
+
+// linkPath returns the path of a link from fileName to target, either
+// relative to fileName, or to the root if rootAbsolute is true. Targets
+// outside the root are always linked to relative to fileName.
+func linkPath(root string, fileName string, target string, rootAbsolute bool) string {
+	if rootAbsolute {
+		path := filepath.ToSlash(relativeTo(target, filepath.Join(root, "root")))
+		if path != ".." && !strings.HasPrefix(path, "../") {
+			return "/" + path
+		}
+	}
+	return filepath.ToSlash(relativeTo(target, fileName))
+}
+
+// rewriteWikiDestination returns the destination of a wiki link to target,
+// in the same style as the original destination. Page names including a
+// directory remain so, and spaces are used instead of hyphens if the
+// original page name contained them.
+func rewriteWikiDestination(original string, root string, target string) string {
+	page, heading, hasHeading := strings.Cut(original, "#")
+	newPage := makeWikiLink(filepath.Base(target))
+	if strings.Contains(page, "/") {
+		newPage = makeWikiLink(filepath.ToSlash(relativeTo(target, filepath.Join(root, "root"))))
+	}
+	if strings.Contains(page, " ") {
+		newPage = strings.ReplaceAll(newPage, "-", " ")
+	}
+	if hasHeading {
+		newPage = fmt.Sprintf("%s#%s", newPage, heading)
+	}
+	return newPage
+}
+
+// applyLinkEdits returns the contents with the given edits applied.
+func applyLinkEdits(contents string, edits []linkEdit) string {
+	sorted := append([]linkEdit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start > sorted[j].start
+	})
+	for _, edit := range sorted {
+		contents = contents[:edit.start] + edit.newDestination + contents[edit.end:]
+	}
+	return contents
+}
@@ -0,0 +1,171 @@
+package commands_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMv(t *testing.T) {
+	inputDir := writeFiles(t, mvInputFiles(), "markasten-input")
+
+	rootCmd := commands.NewRootCmd()
+	rootCmd.SetArgs([]string{
+		"mv",
+		"--debug",
+		"-i",
+		inputDir,
+		filepath.Join(inputDir, "team/bar.md"),
+		filepath.Join(inputDir, "archive/old-bar.md"),
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	_, err := os.Stat(filepath.Join(inputDir, "team/bar.md"))
+	require.True(t, os.IsNotExist(err))
+	for _, outputFile := range []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"# Foo",
+				"Foo mentions [bar](./archive/old-bar.md#heading), [bar again][bar] and [spam](team/spam.md).",
+				"It also mentions [[old-bar]], [[archive/old-bar#Heading|bar]], [[the bar|archive/old-bar]] and [[Bar Title]].",
+				"And [bar by its absolute path](/archive/old-bar.md?plain=1).",
+				"",
+				"[bar]: archive/old-bar.md",
+			},
+		},
+		{
+			name: "team/spam.md",
+			contents: []string{
+				"# Spam",
+				"Spam mentions [bar](../archive/old-bar.md) and `[bar](bar.md)` in code.",
+			},
+		},
+		{
+			name: "archive/old-bar.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- bar",
+				"---",
+				"# Bar Title",
+				"Bar mentions [foo](../foo.md), [spam](../team/spam.md), [itself](#bar-title) and [root](/foo.md).",
+			},
+		},
+	} {
+		actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, outputFile.name))
+		require.NoError(t, err)
+		require.Equal(t, strings.Join(outputFile.contents, "\n"), string(actualOutputBytes))
+	}
+}
+
+func TestMvDryRun(t *testing.T) {
+	inputDir := writeFiles(t, mvInputFiles(), "markasten-input")
+
+	rootCmd := commands.NewRootCmd()
+	output := &bytes.Buffer{}
+	rootCmd.SetOut(output)
+	rootCmd.SetArgs([]string{
+		"mv",
+		"--dry-run",
+		"-i",
+		inputDir,
+		filepath.Join(inputDir, "team/bar.md"),
+		filepath.Join(inputDir, "archive/bar.md"),
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	require.Equal(t, strings.Join([]string{
+		"foo.md:2: ./team/bar.md#heading -> ./archive/bar.md#heading",
+		"foo.md:2: team/bar.md -> archive/bar.md",
+		"foo.md:3: team/bar#Heading -> archive/bar#Heading",
+		"foo.md:3: team/bar -> archive/bar",
+		"foo.md:4: /team/bar.md?plain=1 -> /archive/bar.md?plain=1",
+		"team/bar.md:6: spam.md -> ../team/spam.md",
+		"team/spam.md:2: bar.md -> ../archive/bar.md",
+		"rename team/bar.md -> archive/bar.md",
+		"",
+	}, "\n"), strings.ReplaceAll(output.String(), inputDir+string(filepath.Separator), ""))
+
+	_, err := os.Stat(filepath.Join(inputDir, "team/bar.md"))
+	require.NoError(t, err)
+}
+
+func mvInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"# Foo",
+				"Foo mentions [bar](./team/bar.md#heading), [bar again][bar] and [spam](team/spam.md).",
+				"It also mentions [[bar]], [[team/bar#Heading|bar]], [[the bar|team/bar]] and [[Bar Title]].",
+				"And [bar by its absolute path](/team/bar.md?plain=1).",
+				"",
+				"[bar]: team/bar.md",
+			},
+		},
+		{
+			name: "team/spam.md",
+			contents: []string{
+				"# Spam",
+				"Spam mentions [bar](bar.md) and `[bar](bar.md)` in code.",
+			},
+		},
+		{
+			name: "team/bar.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- bar",
+				"---",
+				"# Bar Title",
+				"Bar mentions [foo](../foo.md), [spam](spam.md), [itself](#bar-title) and [root](/foo.md).",
+			},
+		},
+	}
+}
+
+func TestMvInRepo(t *testing.T) {
+	repoDir := writeFiles(t, []file{
+		{name: ".git/HEAD"},
+		{
+			name: "docs/foo.md",
+			contents: []string{
+				"# Foo",
+				"Foo mentions [bar](/docs/bar.md) and [the readme](/README.md).",
+			},
+		},
+		{
+			name:     "docs/bar.md",
+			contents: []string{"# Bar"},
+		},
+		{
+			name:     "README.md",
+			contents: []string{"# Readme"},
+		},
+	}, "markasten-input")
+	inputDir := filepath.Join(repoDir, "docs")
+
+	rootCmd := commands.NewRootCmd()
+	rootCmd.SetArgs([]string{
+		"mv",
+		"-i",
+		inputDir,
+		filepath.Join(inputDir, "bar.md"),
+		filepath.Join(inputDir, "archive/bar.md"),
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, "foo.md"))
+	require.NoError(t, err)
+	require.Equal(t, strings.Join([]string{
+		"# Foo",
+		"Foo mentions [bar](/docs/archive/bar.md) and [the readme](/README.md).",
+	}, "\n"), string(actualOutputBytes))
+}
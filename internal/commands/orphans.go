@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	orphansInputPath    *string
+	orphansRoot         *string
+	orphansOutputPath   *string
+	orphansFormat       *string
+	orphansNoInbound    *bool
+	orphansNoOutbound   *bool
+	orphansIsolated     *bool
+	orphansCheck        *bool
+	orphansDebugEnabled *bool
+)
+
+// orphanNote is a note listed in the orphans report.
+type orphanNote struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+}
+
+// orphanCategory is a category of note in the orphans report.
+type orphanCategory struct {
+	key   string
+	title string
+	notes []orphanNote
+}
+
+func newOrphansCommand() *cobra.Command {
+	orphansCommand := &cobra.Command{
+		Use:          "orphans",
+		Short:        "Report notes which are not linked to, do not link to anything, or are isolated",
+		RunE:         orphansRunFn,
+		SilenceUsage: true,
+	}
+	orphansInputPath = orphansCommand.Flags().StringP("input", "i", "", "The location of the input files")
+	orphansOutputPath = orphansCommand.Flags().StringP("output", "o", "", "The location of an optional file to write the report to, instead of stdout")
+	orphansRoot = orphansCommand.Flags().String("root", "", rootFlagUsage)
+	orphansFormat = orphansCommand.Flags().String("format", "markdown", "The format of the report, either markdown or json")
+	orphansNoInbound = orphansCommand.Flags().Bool("no-inbound", false, "If set, notes with no inbound links will be reported")
+	orphansNoOutbound = orphansCommand.Flags().Bool("no-outbound", false, "If set, notes with no outbound links will be reported")
+	orphansIsolated = orphansCommand.Flags().Bool("isolated", false, "If set, notes with no links and no tags will be reported")
+	orphansCheck = orphansCommand.Flags().Bool("check", false, checkFlagUsage)
+	orphansDebugEnabled = orphansCommand.Flags().Bool("debug", false, "If set, debug logging will be enabled")
+	debugEnabled = orphansDebugEnabled
+	return orphansCommand
+}
+
+func orphansRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = orphansDebugEnabled
+	debug("orphans called with -i %s and -o %s\n", *orphansInputPath, *orphansOutputPath)
+	if *orphansFormat != "markdown" && *orphansFormat != "json" {
+		return fmt.Errorf("unsupported format %q, expected markdown or json", *orphansFormat)
+	}
+	inputDirEntries, err := newFullDirEntryList(*orphansInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults, err := searchForMarkdownFiles(inputDirEntries, *orphansInputPath)
+	if err != nil {
+		panic(err)
+	}
+	searchResults = excludeFile(searchResults, *orphansOutputPath)
+
+	backlinksByTarget, _ := findBacklinks(searchResults, *orphansInputPath, linkRoot(*orphansRoot, *orphansInputPath), nil)
+	hasOutbound := make(map[string]bool)
+	for _, backlinks := range backlinksByTarget {
+		for _, b := range backlinks {
+			hasOutbound[absPath(b.source.fileName)] = true
+		}
+	}
+
+	// If no categories are selected, all of them are reported.
+	all := !*orphansNoInbound && !*orphansNoOutbound && !*orphansIsolated
+	noInbound := orphanCategory{key: "noInbound", title: "No inbound links"}
+	noOutbound := orphanCategory{key: "noOutbound", title: "No outbound links"}
+	isolated := orphanCategory{key: "isolated", title: "Isolated"}
+	relativeToPath := *orphansOutputPath
+	if relativeToPath == "" {
+		relativeToPath = filepath.Join(*orphansInputPath, "orphans")
+	}
+	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
+		fileBytes, err := os.ReadFile(dirEntry.Name())
+		if err != nil {
+			panic(err)
+		}
+		tags, title := scrapeTagsAndTitle(dirEntry.Name(), fileBytes)
+		relativePath := filepath.ToSlash(relativeTo(dirEntry.Name(), relativeToPath))
+		note := orphanNote{Title: title, Path: relativePath}
+
+		inbound := len(backlinksByTarget[absPath(dirEntry.Name())]) > 0
+		outbound := hasOutbound[absPath(dirEntry.Name())]
+		if !inbound {
+			noInbound.notes = append(noInbound.notes, note)
+		}
+		if !outbound {
+			noOutbound.notes = append(noOutbound.notes, note)
+		}
+		if !inbound && !outbound && len(tags) == 0 {
+			isolated.notes = append(isolated.notes, note)
+		}
+	}
+
+	var categories []orphanCategory
+	if all || *orphansNoInbound {
+		categories = append(categories, noInbound)
+	}
+	if all || *orphansNoOutbound {
+		categories = append(categories, noOutbound)
+	}
+	if all || *orphansIsolated {
+		categories = append(categories, isolated)
+	}
+
+	report := renderOrphansMarkdown(categories)
+	if *orphansFormat == "json" {
+		report = renderOrphansJSON(categories)
+	}
+	return writeOutput(cmd, *orphansOutputPath, report, *orphansCheck)
+}
+
+func renderOrphansMarkdown(categories []orphanCategory) string {
+	sections := []string{"# Orphans\n"}
+	for _, category := range categories {
+		lines := []string{fmt.Sprintf("## %s", category.title)}
+		for _, note := range category.notes {
+			lines = append(lines, fmt.Sprintf("- [%s](%s)", note.Title, note.Path))
+		}
+		sections = append(sections, strings.Join(lines, "\n")+"\n")
+	}
+	return strings.Join(sections, "\n")
+}
+
+func renderOrphansJSON(categories []orphanCategory) string {
+	report := make(map[string][]orphanNote)
+	for _, category := range categories {
+		notes := category.notes
+		if notes == nil {
+			notes = []orphanNote{}
+		}
+		report[category.key] = notes
+	}
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(reportBytes) + "\n"
+}
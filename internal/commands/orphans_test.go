@@ -0,0 +1,187 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrphans(t *testing.T) {
+	for _, tc := range []testCase{
+		basicOrphans(),
+		orphansWithNoInboundFlag(),
+		orphansWithJSONFormat(),
+		orphansWithExistingReport(),
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
+			actualOutputFilePath := filepath.Join(inputDir, tc.outputFiles[0].name)
+
+			rootCmd := commands.NewRootCmd()
+			args := []string{
+				"orphans",
+				"--debug",
+				"-i",
+				inputDir,
+				"-o",
+				actualOutputFilePath,
+			}
+			args = append(args, tc.additionalArgs...)
+			rootCmd.SetArgs(args)
+			require.NoError(t, rootCmd.Execute())
+
+			actualOutputBytes, err := os.ReadFile(actualOutputFilePath)
+			require.NoError(t, err)
+			require.Equal(t, strings.Join(tc.outputFiles[0].contents, "\n"), string(actualOutputBytes))
+		})
+	}
+}
+
+func orphansInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- foo",
+				"---",
+				"# Foo",
+				"Foo mentions [bar](bar.md).",
+			},
+		},
+		{
+			name: "bar.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- bar",
+				"---",
+				"# Bar",
+				"Bar mentions nothing.",
+			},
+		},
+		{
+			name: "spam.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- spam",
+				"---",
+				"# Spam",
+				"Spam has tags, but no links.",
+			},
+		},
+		{
+			name: "team/eggs.md",
+			contents: []string{
+				"# Eggs",
+				"Eggs has no tags, and no links.",
+			},
+		},
+	}
+}
+
+func basicOrphans() testCase {
+	return testCase{
+		name:       "basic orphans",
+		inputFiles: orphansInputFiles(),
+		outputFiles: []file{
+			{
+				name: "orphans.md",
+				contents: []string{
+					"# Orphans",
+					"",
+					"## No inbound links",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"- [Eggs](team/eggs.md)",
+					"",
+					"## No outbound links",
+					"- [Bar](bar.md)",
+					"- [Spam](spam.md)",
+					"- [Eggs](team/eggs.md)",
+					"",
+					"## Isolated",
+					"- [Eggs](team/eggs.md)",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func orphansWithExistingReport() testCase {
+	tc := basicOrphans()
+	tc.name = "orphans with an existing report in the input directory"
+	tc.inputFiles = append(tc.inputFiles, file{
+		name:     "orphans.md",
+		contents: tc.outputFiles[0].contents,
+	})
+	return tc
+}
+
+func orphansWithNoInboundFlag() testCase {
+	return testCase{
+		name:           "orphans with no inbound flag",
+		additionalArgs: []string{"--no-inbound"},
+		inputFiles:     orphansInputFiles(),
+		outputFiles: []file{
+			{
+				name: "orphans.md",
+				contents: []string{
+					"# Orphans",
+					"",
+					"## No inbound links",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"- [Eggs](team/eggs.md)",
+					"",
+				},
+			},
+		},
+	}
+}
+
+func orphansWithJSONFormat() testCase {
+	return testCase{
+		name:           "orphans with json format",
+		additionalArgs: []string{"--format", "json", "--isolated", "--no-outbound"},
+		inputFiles:     orphansInputFiles(),
+		outputFiles: []file{
+			{
+				name: "orphans.json",
+				contents: []string{
+					"{",
+					`  "isolated": [`,
+					"    {",
+					`      "title": "Eggs",`,
+					`      "path": "team/eggs.md"`,
+					"    }",
+					"  ],",
+					`  "noOutbound": [`,
+					"    {",
+					`      "title": "Bar",`,
+					`      "path": "bar.md"`,
+					"    },",
+					"    {",
+					`      "title": "Spam",`,
+					`      "path": "spam.md"`,
+					"    },",
+					"    {",
+					`      "title": "Eggs",`,
+					`      "path": "team/eggs.md"`,
+					"    }",
+					"  ]",
+					"}",
+					"",
+				},
+			},
+		},
+	}
+}
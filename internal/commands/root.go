@@ -8,6 +8,11 @@ func NewRootCmd() *cobra.Command {
 	}
 	rootCmd.AddCommand(newTagsCommand())
 	rootCmd.AddCommand(newBacklinksCommand())
+	rootCmd.AddCommand(newLinksCommand())
+	rootCmd.AddCommand(newOrphansCommand())
+	rootCmd.AddCommand(newGraphCommand())
+	rootCmd.AddCommand(newMentionsCommand())
+	rootCmd.AddCommand(newMvCommand())
 	return rootCmd
 
 }
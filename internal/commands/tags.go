@@ -1,13 +1,15 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -19,12 +21,45 @@ var (
 	capitalize       *bool
 	tagLinks         *bool
 	toc              *bool
+	nestedTags       *bool
+	tagSeparator     *string
+	inlineTags       *bool
+	tagsConfigPath   *string
+	tagKeys          *[]string
+	inject           *bool
+	tagsCheck        *bool
+	tagsTemplatePath *string
+	tagsFormat       *string
+	split            *bool
 )
 
+const (
+	tagsStartMarker = "<!-- markasten:tags:start -->"
+	tagsEndMarker   = "<!-- markasten:tags:end -->"
+)
+
+// tagSection is the section of the tags index listing the files with a tag.
+type tagSection struct {
+	tag         string
+	header      string
+	description string
+	level       int
+	files       []indexedFile
+}
+
+// tagNode is a tag in the hierarchy of nested tags.
+type tagNode struct {
+	tag      string
+	name     string
+	files    []indexedFile
+	children map[string]*tagNode
+}
+
 func newTagsCommand() *cobra.Command {
 	tagsCommand := &cobra.Command{
-		Use:  "tags",
-		RunE: tagsRunFn,
+		Use:          "tags",
+		RunE:         tagsRunFn,
+		SilenceUsage: true,
 	}
 	tagsInputPath = tagsCommand.Flags().StringP("input", "i", "", "The location of the input files")
 	tagsOutputPath = tagsCommand.Flags().StringP("output", "o", "", "The location of the output files")
@@ -34,12 +69,49 @@ func newTagsCommand() *cobra.Command {
 	capitalize = tagsCommand.Flags().Bool("capitalize", false, "If set, tag names in the generated index will have their first character capitalized.")
 	tagLinks = tagsCommand.Flags().Bool("tag-links", false, "If set, links to files in the generated index will be annotated with the list of other tags they have.")
 	toc = tagsCommand.Flags().Bool("toc", false, "If set, a table of contents will be generated containing a link to the heading of each tag")
+	nestedTags = tagsCommand.Flags().Bool("nested-tags", false, "If set, tags containing the tag separator will be nested under their parent tags, which also list the files of their children")
+	tagSeparator = tagsCommand.Flags().String("tag-separator", "/", "The separator between the parts of nested tags")
+	tagKeys = tagsCommand.Flags().StringSlice("tag-keys", defaultTagKeys, "The frontmatter keys to read tags from, such as tags, keywords or categories")
+	tagsConfigPath = tagsCommand.Flags().String("config", "", "The location of an optional tags config file, defaulting to tags.yml in the input directory if it exists")
+	inlineTags = tagsCommand.Flags().Bool("inline-tags", false, "If set, inline #hashtags in the body of each file will be included along with the tags in its frontmatter")
+	inject = tagsCommand.Flags().Bool("inject", false, "If set, the index will replace the content between "+tagsStartMarker+" and "+tagsEndMarker+" in the output file, instead of overwriting it. If the markers are missing, they are appended to the file.")
+	tagsCheck = tagsCommand.Flags().Bool("check", false, checkFlagUsage)
+	tagsFormat = tagsCommand.Flags().String("format", markdownTagsFormat, "The format of the index, one of markdown, json, yaml or csv")
+	tagsTemplatePath = tagsCommand.Flags().String("template", "", "The location of an optional Go text/template to render the index with, instead of the default layout")
+	split = tagsCommand.Flags().Bool("split", false, "If set, the output will be a directory, into which a page for each tag will be written along with an index of the pages")
 	debugEnabled = tagsDebugEnabled
 	return tagsCommand
 }
 
 func tagsRunFn(cmd *cobra.Command, args []string) error {
+	debugEnabled = tagsDebugEnabled
 	debug("tags called with -i %s and -o %s\n", *tagsInputPath, *tagsOutputPath)
+	config, err := loadTagsConfig(*tagsConfigPath, *tagsInputPath)
+	if err != nil {
+		return err
+	}
+	switch *tagsFormat {
+	case markdownTagsFormat:
+	case jsonTagsFormat, yamlTagsFormat, csvTagsFormat:
+		if *tagsTemplatePath != "" || *inject {
+			return fmt.Errorf("--template and --inject can only be used with --format markdown")
+		}
+	default:
+		return fmt.Errorf("unsupported format %q, expected one of markdown, json, yaml or csv", *tagsFormat)
+	}
+	if *split && (*tagsFormat != markdownTagsFormat || *tagsTemplatePath != "" || *inject) {
+		return fmt.Errorf("--split cannot be used with --format, --template or --inject")
+	}
+	if *nestedTags && *tagSeparator == "" {
+		return fmt.Errorf("--tag-separator cannot be empty when using --nested-tags")
+	}
+	if *split && *tagsOutputPath == "" {
+		return fmt.Errorf("--split requires an output directory to be set using -o")
+	}
+	tmpl, err := loadTagsTemplate(*tagsTemplatePath)
+	if err != nil {
+		return err
+	}
 	inputDirEntires, err := newFullDirEntryList(*tagsInputPath)
 	if err != nil {
 		panic(err)
@@ -50,22 +122,45 @@ func tagsRunFn(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		panic(err)
 	}
+	// All of the tags are scraped before any are grouped, so that plural
+	// tags can be grouped with their singular form wherever it is used.
+	type scrapedFile struct {
+		name  string
+		title string
+		tags  []string
+	}
+	var scrapedFiles []scrapedFile
 	for _, dirEntry := range searchResults {
+		if !isMarkdownFile(dirEntry.Name()) {
+			continue
+		}
 		fileBytes, err := os.ReadFile(dirEntry.Name())
 		if err != nil {
 			panic(err)
 		}
 
-		scrapedTags, title := scrapeTagsAndTitle(fileBytes)
-		filesByTags = appendFilesByTags(scrapedTags, filesByTags, title, dirEntry.Name())
+		scrapedTags, title, errs := scrapeFrontmatterTagsAndTitle(dirEntry.Name(), fileBytes, *tagKeys)
+		for _, err := range errs {
+			var fmErr *frontmatterError
+			if errors.As(err, &fmErr) {
+				cmd.PrintErrf("%s:%d:%d: %s\n", dirEntry.Name(), fmErr.line, fmErr.column, fmErr.err)
+				continue
+			}
+			cmd.PrintErrf("%s: %s\n", dirEntry.Name(), err)
+		}
+		if *inlineTags && isMarkdownFile(dirEntry.Name()) {
+			for _, hashtag := range extractHashtags(string(fileBytes)) {
+				if !containsString(scrapedTags, hashtag) {
+					scrapedTags = append(scrapedTags, hashtag)
+				}
+			}
+		}
+		config.addKnownTags(scrapedTags)
+		scrapedFiles = append(scrapedFiles, scrapedFile{name: dirEntry.Name(), title: title, tags: scrapedTags})
 	}
-
-	outputFile, err := os.Create(*tagsOutputPath)
-	if err != nil {
-		panic(err)
+	for _, f := range scrapedFiles {
+		filesByTags = appendFilesByTags(f.tags, filesByTags, f.title, f.name, config)
 	}
-	defer outputFile.Close()
-	writeOrPanic(outputFile, fmt.Sprintf("# %s\n", *title))
 
 	var sortedTags []string
 	for tag, _ := range filesByTags {
@@ -73,63 +168,136 @@ func tagsRunFn(cmd *cobra.Command, args []string) error {
 	}
 	sort.Strings(sortedTags)
 
-	if *toc {
-		writeOrPanic(outputFile, "\n")
-		writeOrPanic(outputFile, "---\n")
-		writeOrPanic(outputFile, "\n")
-		writeOrPanic(outputFile, "## Table of contents\n")
+	var sections []tagSection
+	if *nestedTags {
+		sections = nestedTagSections(filesByTags, sortedTags, config)
+	} else {
 		for _, tag := range sortedTags {
-			header := tagToHeader(tag)
-			writeOrPanic(outputFile, fmt.Sprintf("- [%s](#%s)\n", header, headerToLink(header)))
+			sections = append(sections, tagSection{
+				tag:         tag,
+				header:      config.header(tag, tag),
+				description: config.description(tag),
+				level:       2,
+				files:       filesByTags[tag],
+			})
 		}
-		writeOrPanic(outputFile, "\n")
-		writeOrPanic(outputFile, "---\n")
-		writeOrPanic(outputFile, "\n")
 	}
 
-	for n, tag := range sortedTags {
-		files := filesByTags[tag]
-		writeOrPanic(outputFile, fmt.Sprintf("## %s\n", tagToHeader(tag)))
+	if *split {
+		// Pages are written into the output directory, so the paths of
+		// files are relative to it.
+		index := newTagsIndex(*title, sections, filepath.Join(*tagsOutputPath, tagsSplitIndexName), *wikiLinks)
+		index.TagLinks = *tagLinks
+		return writeTagPages(cmd, index, *tagsOutputPath, *wikiLinks, *tagsCheck)
+	}
+	index := newTagsIndex(*title, sections, *tagsOutputPath, *wikiLinks)
+	index.TOC = *toc
+	index.TagLinks = *tagLinks
+	index.Inject = *inject
+	var contents string
+	switch *tagsFormat {
+	case jsonTagsFormat:
+		contents = renderTagsIndexJSON(index)
+	case yamlTagsFormat:
+		contents = renderTagsIndexYAML(index)
+	case csvTagsFormat:
+		contents = renderTagsIndexCSV(index)
+	default:
+		output := &strings.Builder{}
+		if err := tmpl.Execute(output, index); err != nil {
+			return err
+		}
+		contents = output.String()
+	}
+	if *inject {
+		existing, err := os.ReadFile(*tagsOutputPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			panic(err)
+		}
+		contents, err = replaceMarkedSection(
+			string(existing),
+			tagsStartMarker,
+			tagsEndMarker,
+			fmt.Sprintf("%s\n%s\n%s", tagsStartMarker, contents, tagsEndMarker),
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *tagsOutputPath, err)
+		}
+	}
+	return writeOutput(cmd, *tagsOutputPath, contents, *tagsCheck)
+}
 
-		countedTitles := countTitles(files)
-		for m, f := range files {
-			trailingChar := "\n"
-			if m == len(files)-1 && n == len(sortedTags)-1 {
-				trailingChar = ""
-			}
-			relativePath := relativeTo(f.fileName, *tagsOutputPath)
-			if *wikiLinks {
-				relativePath = makeWikiLink(relativePath)
+// nestedTagSections returns a section for each tag in the hierarchy of
+// nested tags, in depth-first order. Parent tags are included even if no
+// files have them, and list the files of all of their descendants as well
+// as their own.
+func nestedTagSections(filesByTags map[string][]indexedFile, sortedTags []string, config *tagsConfig) []tagSection {
+	root := &tagNode{children: make(map[string]*tagNode)}
+	for _, tag := range sortedTags {
+		var parts []string
+		for _, part := range strings.Split(tag, *tagSeparator) {
+			if part != "" {
+				parts = append(parts, part)
 			}
-			title := f.title
-			if count, ok := countedTitles[f.title]; ok && count > 1 {
-				title = relativePath
-			}
-
-			line := fmt.Sprintf(
-				"- [%s](%s)",
-				title,
-				relativePath,
-			)
-			if *tagLinks {
-				for _, otherTag := range f.otherTags {
-					line = fmt.Sprintf("%s `%s`", line, otherTag)
+		}
+		if len(parts) == 0 {
+			parts = []string{tag}
+		}
+		node := root
+		for i, part := range parts {
+			child, ok := node.children[part]
+			if !ok {
+				child = &tagNode{
+					tag:      strings.Join(parts[:i+1], *tagSeparator),
+					name:     part,
+					children: make(map[string]*tagNode),
 				}
+				node.children[part] = child
 			}
-			line = fmt.Sprintf(
-				"%s%s",
-				line,
-				trailingChar,
-			)
+			node = child
+		}
+		node.files = append(node.files, filesByTags[tag]...)
+	}
 
-			writeOrPanic(outputFile, line)
+	var sections []tagSection
+	var visit func(node *tagNode, level int) []indexedFile
+	visit = func(node *tagNode, level int) []indexedFile {
+		section := tagSection{
+			tag:         node.tag,
+			header:      config.header(node.tag, node.name),
+			description: config.description(node.tag),
+			// Markdown headings can be at most six levels deep.
+			level: level,
 		}
-		if n < len(sortedTags)-1 {
-			writeOrPanic(outputFile, "\n")
+		if section.level > 6 {
+			section.level = 6
 		}
+		sections = append(sections, section)
+		n := len(sections) - 1
+		files := append([]indexedFile(nil), node.files...)
+		for _, name := range sortedChildNames(node) {
+			for _, f := range visit(node.children[name], level+1) {
+				if !containsFile(files, f.fileName) {
+					files = append(files, f)
+				}
+			}
+		}
+		sections[n].files = files
+		return files
+	}
+	for _, name := range sortedChildNames(root) {
+		visit(root.children[name], 2)
 	}
+	return sections
+}
 
-	return nil
+func sortedChildNames(node *tagNode) []string {
+	var names []string
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func tagToHeader(tag string) string {
@@ -139,58 +307,34 @@ func tagToHeader(tag string) string {
 	return tag
 }
 
-func scrapeTagsAndTitle(fileBytes []byte) ([]string, string) {
-	var scrapedTags []string
-	title := ""
-	lines := strings.Split(string(fileBytes), "\n")
-	if firstNonEmptyLine(lines) != "---" {
-		debug("first line was %s, no tags detected", lines[0])
-		return scrapedTags, title
-	}
-
-	foundYaml := false
-	finishedYaml := false
-	var yamlLines []string
-	for _, line := range lines {
-		if len(line) > 2 && line[0:2] == "# " {
-			title = line[2:]
-			break
-		}
-		if line == "---" {
-			if foundYaml {
-				// If we had previously found yaml, this line
-				// marks the end of the yaml.
-				finishedYaml = true
-			}
-			if !foundYaml {
-				// if we haven't found yaml yet, this line
-				// marks the beginning of yaml
-				foundYaml = true
-			}
-			continue
-		}
-		if len(line) == 0 {
-			continue
-		}
-		if foundYaml && !finishedYaml {
-			yamlLines = append(yamlLines, line)
-		}
-	}
-	debug("found yaml:")
-	for _, line := range yamlLines {
-		debug(line)
-	}
+// defaultTagKeys are the frontmatter keys tags are read from by default.
+var defaultTagKeys = []string{"tags"}
 
-	fm := frontmatter{}
-	err := yaml.Unmarshal([]byte(strings.Join(yamlLines, "\n")), &fm)
-	if err != nil {
-		debug("error unmarshalling yaml: %s", err)
-		return scrapedTags, title
-	}
-	return fm.Tags, title
+func scrapeTagsAndTitle(fileName string, fileBytes []byte) ([]string, string) {
+	scrapedTags, title, _ := scrapeFrontmatterTagsAndTitle(fileName, fileBytes, defaultTagKeys)
+	return scrapedTags, title
+}
+
+// scrapeFrontmatterTagsAndTitle returns the tags in the given keys of the
+// frontmatter of a file, and its title. Any problems with the frontmatter
+// are returned as errors.
+func scrapeFrontmatterTagsAndTitle(fileName string, fileBytes []byte, tagKeys []string) ([]string, string, []error) {
+	n := parseNote(fileBytes)
+	scrapedTags, errs := n.tags(tagKeys)
+	return scrapedTags, n.title(fileName), errs
 }
 
-func appendFilesByTags(scrapedTags []string, filesByTags map[string][]indexedFile, title string, name string) map[string][]indexedFile {
+// appendFilesByTags adds a file to the files for each of its tags. If config
+// is not nil, the tags are canonicalized first, so that aliases and
+// variations of a tag are grouped together.
+func appendFilesByTags(
+	scrapedTags []string,
+	filesByTags map[string][]indexedFile,
+	title string,
+	name string,
+	config *tagsConfig,
+) map[string][]indexedFile {
+	scrapedTags = config.canonicalize(scrapedTags)
 	for _, tagName := range scrapedTags {
 		tagName := tagName
 		file := indexedFile{
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTagsConfigName is the name of the tags config file which is used
+// if it is present in the root of the input files.
+const defaultTagsConfigName = "tags.yml"
+
+// tagsConfig configures how tags are normalized, which tags are aliases of
+// others, and how tags are displayed in the index.
+type tagsConfig struct {
+	Normalize tagNormalization     `yaml:"normalize"`
+	Tags      map[string]tagConfig `yaml:"tags"`
+
+	// canonicalTags maps normalized tags and aliases to the canonical tag
+	// they refer to.
+	canonicalTags map[string]string
+	// knownTags are the normalized tags which are used in files or the
+	// config, which plural tags can be replaced with.
+	knownTags map[string]bool
+}
+
+// tagNormalization is the policy applied to tags before they are grouped.
+type tagNormalization struct {
+	// CaseFold lowercases tags.
+	CaseFold bool `yaml:"caseFold"`
+	// Separator replaces spaces and hyphens between words in tags with
+	// either hyphens or spaces, and can be one of "hyphen" or "space".
+	Separator string `yaml:"separator"`
+	// Singular replaces plural tags with their singular form, if the
+	// singular form is also used as a tag.
+	Singular bool `yaml:"singular"`
+}
+
+// tagConfig configures a canonical tag.
+type tagConfig struct {
+	Aliases     []string `yaml:"aliases"`
+	DisplayName string   `yaml:"displayName"`
+	Description string   `yaml:"description"`
+}
+
+// loadTagsConfig reads the tags config at the given path or, if the path is
+// empty, from tags.yml in the root of the input files if it exists. If there
+// is no config, nil is returned.
+func loadTagsConfig(path string, inputPath string) (*tagsConfig, error) {
+	if path == "" {
+		path = filepath.Join(inputPath, defaultTagsConfigName)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &tagsConfig{}
+	if err := yaml.Unmarshal(configBytes, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch config.Normalize.Separator {
+	case "", "hyphen", "space":
+	default:
+		return nil, fmt.Errorf("%s: unsupported separator %q, expected hyphen or space", path, config.Normalize.Separator)
+	}
+	config.canonicalTags = make(map[string]string)
+	config.knownTags = make(map[string]bool)
+	for tag, c := range config.Tags {
+		config.canonicalTags[config.normalize(tag)] = tag
+		config.knownTags[config.normalize(tag)] = true
+		for _, alias := range c.Aliases {
+			config.canonicalTags[config.normalize(alias)] = tag
+			config.knownTags[config.normalize(alias)] = true
+		}
+	}
+	debug("loaded tags config from %s", path)
+	return config, nil
+}
+
+// addKnownTags records tags used in files, so that plural tags can be
+// replaced with their singular form if it is also used. A nil config
+// ignores the tags.
+func (c *tagsConfig) addKnownTags(tags []string) {
+	if c == nil {
+		return
+	}
+	for _, tag := range tags {
+		c.knownTags[c.normalize(tag)] = true
+	}
+}
+
+// canonicalize returns the canonical form of the given tags, with any
+// duplicates removed. A nil config leaves the tags unchanged.
+func (c *tagsConfig) canonicalize(tags []string) []string {
+	if c == nil {
+		return tags
+	}
+	var canonical []string
+	for _, tag := range tags {
+		tag = c.normalize(tag)
+		if c.Normalize.Singular {
+			// Words such as "news" and "kubernetes" end in an s but
+			// are not plurals, so tags are only made singular if the
+			// singular form is a tag in its own right.
+			if singular := singularize(tag); c.knownTags[singular] {
+				tag = singular
+			}
+		}
+		if canonicalTag, ok := c.canonicalTags[tag]; ok {
+			tag = canonicalTag
+		}
+		if !containsString(canonical, tag) {
+			canonical = append(canonical, tag)
+		}
+	}
+	return canonical
+}
+
+// normalize applies the case and separator normalization policy of the
+// config to a tag.
+func (c *tagsConfig) normalize(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if c.Normalize.CaseFold {
+		tag = strings.ToLower(tag)
+	}
+	switch c.Normalize.Separator {
+	case "hyphen":
+		tag = strings.Join(strings.Fields(tag), "-")
+	case "space":
+		tag = strings.Join(strings.Fields(strings.ReplaceAll(tag, "-", " ")), " ")
+	}
+	return tag
+}
+
+// header returns the heading for a tag in the index, which is its display
+// name if it has one.
+func (c *tagsConfig) header(tag string, name string) string {
+	if c != nil && c.Tags[tag].DisplayName != "" {
+		return c.Tags[tag].DisplayName
+	}
+	return tagToHeader(name)
+}
+
+// description returns the description of a tag, if it has one.
+func (c *tagsConfig) description(tag string) string {
+	if c == nil {
+		return ""
+	}
+	return strings.TrimSpace(c.Tags[tag].Description)
+}
+
+// singularize returns the singular form of the last word of an English
+// tag, using simple suffix rules which are sufficient for most tags.
+func singularize(tag string) string {
+	lower := strings.ToLower(tag)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 4:
+		if strings.HasSuffix(tag, "S") {
+			return tag[:len(tag)-3] + "Y"
+		}
+		return tag[:len(tag)-3] + "y"
+	case strings.HasSuffix(lower, "sses"),
+		strings.HasSuffix(lower, "shes"),
+		strings.HasSuffix(lower, "ches"),
+		strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "zes"):
+		return tag[:len(tag)-2]
+	case strings.HasSuffix(lower, "s") &&
+		!strings.HasSuffix(lower, "ss") &&
+		!strings.HasSuffix(lower, "us") &&
+		!strings.HasSuffix(lower, "is") &&
+		len(lower) > 3:
+		return tag[:len(tag)-1]
+	}
+	return tag
+}
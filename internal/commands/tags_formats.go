@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	markdownTagsFormat = "markdown"
+	jsonTagsFormat     = "json"
+	yamlTagsFormat     = "yaml"
+	csvTagsFormat      = "csv"
+)
+
+func renderTagsIndexJSON(index tagsIndex) string {
+	tags := index.Tags
+	if tags == nil {
+		tags = []tagsIndexTag{}
+	}
+	tagsBytes, err := json.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(tagsBytes) + "\n"
+}
+
+func renderTagsIndexYAML(index tagsIndex) string {
+	tags := index.Tags
+	if tags == nil {
+		tags = []tagsIndexTag{}
+	}
+	var builder strings.Builder
+	encoder := yaml.NewEncoder(&builder)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(tags); err != nil {
+		panic(err)
+	}
+	if err := encoder.Close(); err != nil {
+		panic(err)
+	}
+	return builder.String()
+}
+
+// renderTagsIndexCSV renders a row for each file with each tag, with the
+// other tags of the file separated by semicolons.
+func renderTagsIndexCSV(index tagsIndex) string {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	records := [][]string{{"tag", "title", "path", "otherTags"}}
+	for _, tag := range index.Tags {
+		for _, f := range tag.Files {
+			records = append(records, []string{tag.Tag, f.Title, f.Path, strings.Join(f.OtherTags, ";")})
+		}
+	}
+	if err := writer.WriteAll(records); err != nil {
+		panic(err)
+	}
+	return builder.String()
+}
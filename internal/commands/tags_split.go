@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// tagsSplitIndexName is the name of the index of tag pages written when the
+// tags index is split into a page per tag.
+const tagsSplitIndexName = "README.md"
+
+// writeTagPages writes a page for each tag in the index into the output
+// directory, along with an index linking to each page. The paths of files in
+// the index must be relative to the output directory. If wikiLinks is true,
+// the index links to the pages without their extension. If check is true, the
+// pages are not written, and instead an error is returned if any of them are
+// out of date.
+func writeTagPages(cmd *cobra.Command, index tagsIndex, dir string, wikiLinks bool, check bool) error {
+	if !check {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			panic(err)
+		}
+	}
+	pageNames := tagPageNames(index.Tags)
+	stale := 0
+	write := func(name string, contents string) {
+		if err := writeOutput(cmd, filepath.Join(dir, name), contents, check); err != nil {
+			stale++
+		}
+	}
+
+	lines := []string{fmt.Sprintf("# %s", index.Title)}
+	for _, tag := range index.Tags {
+		notes := "notes"
+		if tag.Count == 1 {
+			notes = "note"
+		}
+		link := pageNames[tag.Tag]
+		if wikiLinks {
+			link = makeWikiLink(link)
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%s- [%s](%s) (%d %s)",
+			strings.Repeat("  ", tag.Depth),
+			tag.Header,
+			link,
+			tag.Count,
+			notes,
+		))
+	}
+	write(tagsSplitIndexName, strings.Join(lines, "\n")+"\n")
+
+	for _, tag := range index.Tags {
+		write(pageNames[tag.Tag], renderTagPage(tag, index.TagLinks))
+	}
+	if stale > 0 {
+		return fmt.Errorf("found %d out of date files in %s", stale, dir)
+	}
+	return nil
+}
+
+// renderTagPage renders the page listing the files with a tag.
+func renderTagPage(tag tagsIndexTag, tagLinks bool) string {
+	lines := []string{fmt.Sprintf("# %s", tag.Header)}
+	if tag.Description != "" {
+		lines = append(lines, tag.Description, "")
+	}
+	for _, f := range tag.Files {
+		line := fmt.Sprintf("- [%s](%s)", f.Title, f.Path)
+		if tagLinks {
+			for _, otherTag := range f.OtherTags {
+				line = fmt.Sprintf("%s `%s`", line, otherTag)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// tagPageNames returns the file name of the page for each tag, which is a
+// slug of the tag made up of lowercase letters, digits and hyphens. Tags
+// with the same slug as an earlier tag are given the lowest numbered suffix
+// which is not used by another page, as is a tag whose page would have the
+// same name as the index.
+func tagPageNames(tags []tagsIndexTag) map[string]string {
+	names := make(map[string]string)
+	used := map[string]bool{
+		strings.ToLower(strings.TrimSuffix(tagsSplitIndexName, filepath.Ext(tagsSplitIndexName))): true,
+	}
+	// The first tag with each slug is given it before any suffixes are
+	// chosen, so that a suffixed name cannot be the slug of another tag.
+	var duplicates []tagsIndexTag
+	for _, tag := range tags {
+		slug := tagSlug(tag.Tag)
+		if used[slug] {
+			duplicates = append(duplicates, tag)
+			continue
+		}
+		used[slug] = true
+		names[tag.Tag] = slug + ".md"
+	}
+	for _, tag := range duplicates {
+		slug := tagSlug(tag.Tag)
+		name := slug
+		for i := 1; used[name]; i++ {
+			name = fmt.Sprintf("%s-%d", slug, i)
+		}
+		used[name] = true
+		names[tag.Tag] = name + ".md"
+	}
+	return names
+}
+
+// tagSlug returns a slug of a tag which is safe to use as a file name.
+func tagSlug(tag string) string {
+	var builder strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && builder.Len() > 0 {
+				builder.WriteRune('-')
+			}
+			builder.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	if builder.Len() == 0 {
+		return "tag"
+	}
+	return builder.String()
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// defaultTagsTemplate is the template the tags index is rendered with if no
+// other template is given.
+const defaultTagsTemplate = `{{ if not .Inject }}# {{ .Title }}
+{{ end }}{{ if .TOC }}
+---
+
+## Table of contents
+{{ range .Tags }}{{ repeat "  " .Depth }}- [{{ .Header }}](#{{ .Anchor }})
+{{ end }}
+---
+
+{{ end }}
+{{- range $i, $tag := .Tags }}
+{{- repeat "#" .Level }} {{ .Header }}
+{{ with .Description }}{{ . }}
+
+{{ end }}
+{{- range $j, $file := .Files }}- [{{ .Title }}]({{ .Path }})
+{{- if $.TagLinks }}{{ range .OtherTags }} ` + "`{{ . }}`" + `{{ end }}{{ end }}
+{{- if not (and (last $i $.Tags) (last $j $tag.Files)) }}
+{{ end }}
+{{- end }}
+{{- if not (last $i $.Tags) }}
+{{ end }}
+{{- end }}`
+
+// tagsIndex is the data the tags index template is rendered with.
+type tagsIndex struct {
+	// Title is the title of the index.
+	Title string
+	// TOC is true if a table of contents should be included.
+	TOC bool
+	// TagLinks is true if the other tags of each file should be listed.
+	TagLinks bool
+	// Inject is true if the index will be injected into an existing file,
+	// which has a title of its own.
+	Inject bool
+	// Tags are the sections of the index for each tag, sorted by tag. If
+	// tags are nested, the sections are in depth-first order.
+	Tags []tagsIndexTag
+}
+
+// tagsIndexTag is the section of the tags index for a tag.
+type tagsIndexTag struct {
+	// Tag is the tag, which is its full path if tags are nested.
+	Tag string `json:"tag" yaml:"tag"`
+	// Header is the heading of the section.
+	Header string `json:"-" yaml:"-"`
+	// Anchor is the anchor GitHub generates for the heading.
+	Anchor string `json:"-" yaml:"-"`
+	// Description is the description of the tag from the tags config.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Level is the level of the heading, from 2 to 6.
+	Level int `json:"-" yaml:"-"`
+	// Depth is the depth of the tag when tags are nested, starting at 0
+	// and limited by the level of its heading.
+	Depth int `json:"-" yaml:"-"`
+	// Count is the number of files with the tag.
+	Count int             `json:"count" yaml:"count"`
+	Files []tagsIndexFile `json:"files" yaml:"files"`
+}
+
+// tagsIndexFile is a file listed in the tags index.
+type tagsIndexFile struct {
+	// Title is the title of the file, or its path if other files with the
+	// tag have the same title.
+	Title string `json:"title" yaml:"title"`
+	// Path is the path of the file relative to the index.
+	Path string `json:"path" yaml:"path"`
+	// OtherTags are the other tags the file has.
+	OtherTags []string `json:"otherTags" yaml:"otherTags"`
+}
+
+var tagsTemplateFuncs = template.FuncMap{
+	"repeat": func(s string, count int) string {
+		if count < 0 {
+			return ""
+		}
+		return strings.Repeat(s, count)
+	},
+	// last reports whether i is the index of the last item of a list.
+	"last": func(i int, list interface{}) bool {
+		return i == reflect.ValueOf(list).Len()-1
+	},
+}
+
+// loadTagsTemplate parses the tags index template at the given path, or the
+// default template if the path is empty.
+func loadTagsTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return template.Must(template.New("tags").Funcs(tagsTemplateFuncs).Parse(defaultTagsTemplate)), nil
+	}
+	templateBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(tagsTemplateFuncs).Parse(string(templateBytes))
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// newTagsIndex returns the data for the tags index made up of the given
+// sections, with the paths of files relative to the output path.
+func newTagsIndex(title string, sections []tagSection, outputPath string, wikiLinks bool) tagsIndex {
+	index := tagsIndex{Title: title}
+	anchorCounts := make(map[string]int)
+	for _, section := range sections {
+		anchor := headerToLink(section.header)
+		if count := anchorCounts[anchor]; count > 0 {
+			// GitHub suffixes the anchors of headings which are
+			// repeated, which nested tags can be.
+			anchorCounts[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, count)
+		} else {
+			anchorCounts[anchor]++
+		}
+		tag := tagsIndexTag{
+			Tag:         section.tag,
+			Header:      section.header,
+			Anchor:      anchor,
+			Description: section.description,
+			Level:       section.level,
+			Depth:       section.level - 2,
+			Count:       len(section.files),
+		}
+		countedTitles := countTitles(section.files)
+		for _, f := range section.files {
+			relativePath := relativeTo(f.fileName, outputPath)
+			if wikiLinks {
+				relativePath = makeWikiLink(relativePath)
+			}
+			title := f.title
+			if count, ok := countedTitles[f.title]; ok && count > 1 {
+				title = relativePath
+			}
+			tag.Files = append(tag.Files, tagsIndexFile{
+				Title:     title,
+				Path:      relativePath,
+				OtherTags: append([]string{}, f.otherTags...),
+			})
+		}
+		index.Tags = append(index.Tags, tag)
+	}
+	return index
+}
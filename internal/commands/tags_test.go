@@ -1,8 +1,10 @@
 package commands_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/andykuszyk/markasten/internal/commands"
@@ -23,10 +25,23 @@ func TestTags(t *testing.T) {
 		tagsWithFilesInNestedSubDirectories(),
 		tagsWithFilesInSubDirectoriesWithSameNames(),
 		tagsWithFilesInDotDirectory(),
+		tagsIgnoresNonMarkdownFiles(),
 		fileWithNoTagsAndBacktickedText(),
 		tocFlag(),
 		tocFlagWithColonInTag(),
 		tocFlagWithSpaceInTag(),
+		nestedTags(),
+		nestedTagsWithTocAndCustomSeparator(),
+		inlineTags(),
+		tagsWithConfig(),
+		tagsWithScalarStringsAndCustomKeys(),
+		tagsWithTOMLAndJSONFrontmatter(),
+		tagsWithTitlesFromFrontmatterHeadingsAndFileNames(),
+		tagsAsJSON(),
+		tagsAsYAML(),
+		tagsAsYAMLWithDescriptions(),
+		tagsAsYAMLWithNoTags(),
+		tagsAsCSV(),
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			inputDir := writeFiles(t, tc.inputFiles, "markasten-input")
@@ -515,6 +530,53 @@ func basicTagsWithWikiLinks() testCase {
 	}
 }
 
+func tagsIgnoresNonMarkdownFiles() testCase {
+	return testCase{
+		name: "tags ignores non-markdown files",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- foo",
+					"---",
+					"",
+					"# Foo",
+				},
+			},
+			{
+				name: "docs/package.json",
+				contents: []string{
+					"{",
+					`  "name": "docs",`,
+					`  "tags": ["fromjson"]`,
+					"}",
+				},
+			},
+			{
+				name: "notes.txt",
+				contents: []string{
+					"---",
+					"tags:",
+					"- fromtext",
+					"---",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## foo",
+					"- [Foo](foo.md)",
+				},
+			},
+		},
+	}
+}
+
 func tagsWithFilesInDotDirectory() testCase {
 	return testCase{
 		name: "tags with files in sub directories",
@@ -926,3 +988,1163 @@ func tocFlagWithSpaceInTag() testCase {
 		},
 	}
 }
+
+func nestedTagsInputFiles(separator string) []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- team" + separator + "foo",
+				"- lang" + separator + "go",
+				"---",
+				"# Foo",
+			},
+		},
+		{
+			name: "bar.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- team" + separator + "bar",
+				"- team",
+				"---",
+				"# Bar",
+			},
+		},
+		{
+			name: "spam.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- lang" + separator + "go" + separator + "foo",
+				"---",
+				"# Spam",
+			},
+		},
+	}
+}
+
+func nestedTags() testCase {
+	return testCase{
+		name:           "nested tags",
+		additionalArgs: []string{"--nested-tags"},
+		inputFiles:     nestedTagsInputFiles("/"),
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## lang",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"",
+					"### go",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"",
+					"#### foo",
+					"- [Spam](spam.md)",
+					"",
+					"## team",
+					"- [Bar](bar.md)",
+					"- [Foo](foo.md)",
+					"",
+					"### bar",
+					"- [Bar](bar.md)",
+					"",
+					"### foo",
+					"- [Foo](foo.md)",
+				},
+			},
+		},
+	}
+}
+
+func nestedTagsWithTocAndCustomSeparator() testCase {
+	return testCase{
+		name:           "nested tags with toc and custom separator",
+		additionalArgs: []string{"--nested-tags", "--tag-separator", ".", "--toc", "--capitalize"},
+		inputFiles:     nestedTagsInputFiles("."),
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"",
+					"---",
+					"",
+					"## Table of contents",
+					"- [Lang](#Lang)",
+					"  - [Go](#Go)",
+					"    - [Foo](#Foo)",
+					"- [Team](#Team)",
+					"  - [Bar](#Bar)",
+					"  - [Foo](#Foo-1)",
+					"",
+					"---",
+					"",
+					"## Lang",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"",
+					"### Go",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+					"",
+					"#### Foo",
+					"- [Spam](spam.md)",
+					"",
+					"## Team",
+					"- [Bar](bar.md)",
+					"- [Foo](foo.md)",
+					"",
+					"### Bar",
+					"- [Bar](bar.md)",
+					"",
+					"### Foo",
+					"- [Foo](foo.md)",
+				},
+			},
+		},
+	}
+}
+
+func inlineTags() testCase {
+	return testCase{
+		name:           "inline tags",
+		additionalArgs: []string{"--inline-tags"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- foo",
+					"---",
+					"# Foo",
+					"Foo is about #onboarding, and #team/foo.",
+					"#foo is already in the frontmatter.",
+					"",
+					"## Heading",
+					"It is not about `#code`, issue #123, C# or [a link](#link-anchor).",
+					"Nor [another link](bar.md#anchor), https://example.com/page#fragment or &#123;.",
+					"",
+					"```",
+					"#fenced",
+					"```",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- bar",
+					"---",
+					"# Bar",
+					"Bar is also about #onboarding.",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## bar",
+					"- [Bar](bar.md)",
+					"",
+					"## foo",
+					"- [Foo](foo.md)",
+					"",
+					"## onboarding",
+					"- [Bar](bar.md)",
+					"- [Foo](foo.md)",
+					"",
+					"## team/foo",
+					"- [Foo](foo.md)",
+				},
+			},
+		},
+	}
+}
+
+func tagsWithConfig() testCase {
+	return testCase{
+		name:           "tags with config",
+		additionalArgs: []string{"--tag-links"},
+		inputFiles: []file{
+			{
+				name: "tags.yml",
+				contents: []string{
+					"normalize:",
+					"  caseFold: true",
+					"  separator: hyphen",
+					"  singular: true",
+					"tags:",
+					"  kubernetes:",
+					"    aliases:",
+					"    - k8s",
+					"    - kube",
+					"    displayName: Kubernetes",
+					"    description: Notes about running containers.",
+				},
+			},
+			{
+				name: "foo.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- k8s",
+					"- How To",
+					"---",
+					"# Foo",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- Kubernetes",
+					"- kube",
+					"- how-tos",
+					"---",
+					"# Bar",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"---",
+					"tags:",
+					"- Guides",
+					"- News",
+					"---",
+					"# Spam",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## guides",
+					"- [Spam](spam.md) `news`",
+					"",
+					"## how-to",
+					"- [Bar](bar.md) `kubernetes`",
+					"- [Foo](foo.md) `kubernetes`",
+					"",
+					"## Kubernetes",
+					"Notes about running containers.",
+					"",
+					"- [Bar](bar.md) `how-to`",
+					"- [Foo](foo.md) `how-to`",
+					"",
+					"## news",
+					"- [Spam](spam.md) `guides`",
+				},
+			},
+		},
+	}
+}
+
+func tagsWithScalarStringsAndCustomKeys() testCase {
+	return testCase{
+		name:           "tags with scalar strings and custom keys",
+		additionalArgs: []string{"--tag-keys", "tags,keywords,categories"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"---",
+					"tags: onboarding, how-to",
+					"categories: [guides]",
+					"---",
+					"# Foo",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"---",
+					"keywords: onboarding reference",
+					"---",
+					"# Bar",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"---",
+					"tag: ignored",
+					"---",
+					"# Spam",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## guides",
+					"- [Foo](foo.md)",
+					"",
+					"## how-to",
+					"- [Foo](foo.md)",
+					"",
+					"## onboarding",
+					"- [Bar](bar.md)",
+					"- [Foo](foo.md)",
+					"",
+					"## reference",
+					"- [Bar](bar.md)",
+				},
+			},
+		},
+	}
+}
+
+func tagsWithTOMLAndJSONFrontmatter() testCase {
+	return testCase{
+		name:           "tags with TOML and JSON frontmatter",
+		additionalArgs: []string{"--tag-keys", "tags,categories"},
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"+++",
+					"# Hugo front matter",
+					"title = 'Foo'",
+					"date = 2023-01-02T15:04:05Z",
+					"tags = [",
+					"  \"hugo\", # the static site generator",
+					"  \"how-to\",",
+					"]",
+					"",
+					"[params]",
+					"categories = [\"ignored\"]",
+					"+++",
+					"# Foo",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"{",
+					"  \"title\": \"Bar\",",
+					"  \"tags\": [\"hugo\", \"reference\"],",
+					"  \"categories\": \"guides\"",
+					"}",
+					"# Bar",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"{{< note >}}",
+					"# Spam",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## guides",
+					"- [Bar](bar.md)",
+					"",
+					"## how-to",
+					"- [Foo](foo.md)",
+					"",
+					"## hugo",
+					"- [Bar](bar.md)",
+					"- [Foo](foo.md)",
+					"",
+					"## reference",
+					"- [Bar](bar.md)",
+				},
+			},
+		},
+	}
+}
+
+func tagsWithTitlesFromFrontmatterHeadingsAndFileNames() testCase {
+	return testCase{
+		name: "tags with titles from frontmatter, headings and file names",
+		inputFiles: []file{
+			{
+				name: "foo.md",
+				contents: []string{
+					"\ufeff---\r",
+					"# A comment, not a title\r",
+					"tags: [notes]\r",
+					"---\r",
+					"# Foo\r",
+				},
+			},
+			{
+				name: "bar.md",
+				contents: []string{
+					"---",
+					"title: Bar Title",
+					"tags: notes",
+					"...",
+					"# Bar",
+				},
+			},
+			{
+				name: "spam.md",
+				contents: []string{
+					"---",
+					"tags: [notes]",
+					"---",
+					"Spam",
+					"====",
+					"",
+					"---",
+					"tags: [ignored]",
+					"---",
+				},
+			},
+			{
+				name: "eggs.md",
+				contents: []string{
+					"---",
+					"tags: [notes]",
+					"---",
+					"Eggs has no heading.",
+				},
+			},
+		},
+		outputFiles: []file{
+			{
+				name: "index.md",
+				contents: []string{
+					"# Index",
+					"## notes",
+					"- [Bar Title](bar.md)",
+					"- [eggs](eggs.md)",
+					"- [Foo](foo.md)",
+					"- [Spam](spam.md)",
+				},
+			},
+		},
+	}
+}
+
+func tagsFormatInputFiles() []file {
+	return []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags: [foo, spam]",
+				"---",
+				"# Foo",
+			},
+		},
+		{
+			name: "team/bar.md",
+			contents: []string{
+				"---",
+				"tags: [spam]",
+				"---",
+				"# Bar, \"the second\"",
+			},
+		},
+	}
+}
+
+func tagsAsJSON() testCase {
+	return testCase{
+		name:           "tags as json",
+		additionalArgs: []string{"--format", "json"},
+		inputFiles:     tagsFormatInputFiles(),
+		outputFiles: []file{
+			{
+				name: "index.json",
+				contents: []string{
+					`[`,
+					`  {`,
+					`    "tag": "foo",`,
+					`    "count": 1,`,
+					`    "files": [`,
+					`      {`,
+					`        "title": "Foo",`,
+					`        "path": "foo.md",`,
+					`        "otherTags": [`,
+					`          "spam"`,
+					`        ]`,
+					`      }`,
+					`    ]`,
+					`  },`,
+					`  {`,
+					`    "tag": "spam",`,
+					`    "count": 2,`,
+					`    "files": [`,
+					`      {`,
+					`        "title": "Foo",`,
+					`        "path": "foo.md",`,
+					`        "otherTags": [`,
+					`          "foo"`,
+					`        ]`,
+					`      },`,
+					`      {`,
+					`        "title": "Bar, \"the second\"",`,
+					`        "path": "team/bar.md",`,
+					`        "otherTags": []`,
+					`      }`,
+					`    ]`,
+					`  }`,
+					`]`,
+					``,
+				},
+			},
+		},
+	}
+}
+
+func tagsAsYAML() testCase {
+	return testCase{
+		name:           "tags as yaml",
+		additionalArgs: []string{"--format", "yaml"},
+		inputFiles:     tagsFormatInputFiles(),
+		outputFiles: []file{
+			{
+				name: "index.yml",
+				contents: []string{
+					`- tag: foo`,
+					`  count: 1`,
+					`  files:`,
+					`    - title: Foo`,
+					`      path: foo.md`,
+					`      otherTags:`,
+					`        - spam`,
+					`- tag: spam`,
+					`  count: 2`,
+					`  files:`,
+					`    - title: Foo`,
+					`      path: foo.md`,
+					`      otherTags:`,
+					`        - foo`,
+					`    - title: Bar, "the second"`,
+					`      path: team/bar.md`,
+					`      otherTags: []`,
+					``,
+				},
+			},
+		},
+	}
+}
+
+func tagsAsYAMLWithDescriptions() testCase {
+	return testCase{
+		name:           "tags as yaml with descriptions",
+		additionalArgs: []string{"--format", "yaml"},
+		inputFiles: append(tagsFormatInputFiles(), file{
+			name: "tags.yml",
+			contents: []string{
+				"tags:",
+				"  spam:",
+				"    description: Notes about spam.",
+			},
+		}),
+		outputFiles: []file{
+			{
+				name: "index.yml",
+				contents: []string{
+					`- tag: foo`,
+					`  count: 1`,
+					`  files:`,
+					`    - title: Foo`,
+					`      path: foo.md`,
+					`      otherTags:`,
+					`        - spam`,
+					`- tag: spam`,
+					`  description: Notes about spam.`,
+					`  count: 2`,
+					`  files:`,
+					`    - title: Foo`,
+					`      path: foo.md`,
+					`      otherTags:`,
+					`        - foo`,
+					`    - title: Bar, "the second"`,
+					`      path: team/bar.md`,
+					`      otherTags: []`,
+					``,
+				},
+			},
+		},
+	}
+}
+
+func tagsAsYAMLWithNoTags() testCase {
+	return testCase{
+		name:           "tags as yaml with no tags",
+		additionalArgs: []string{"--format", "yaml"},
+		inputFiles: []file{
+			{
+				name:     "foo.md",
+				contents: []string{"# Foo"},
+			},
+		},
+		outputFiles: []file{
+			{
+				name:     "index.yml",
+				contents: []string{"[]", ""},
+			},
+		},
+	}
+}
+
+func tagsAsCSV() testCase {
+	return testCase{
+		name:           "tags as csv",
+		additionalArgs: []string{"--format", "csv"},
+		inputFiles:     tagsFormatInputFiles(),
+		outputFiles: []file{
+			{
+				name: "index.csv",
+				contents: []string{
+					`tag,title,path,otherTags`,
+					`foo,Foo,foo.md,spam`,
+					`spam,Foo,foo.md,foo`,
+					`spam,"Bar, ""the second""",team/bar.md,`,
+					``,
+				},
+			},
+		},
+	}
+}
+
+func TestTagsReportsInvalidFrontmatter(t *testing.T) {
+	inputDir := writeFiles(t, []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"  team: foo",
+				"---",
+				"# Foo",
+			},
+		},
+		{
+			name: "bar.md",
+			contents: []string{
+				"---",
+				"tags: [bar",
+				"---",
+				"# Bar",
+			},
+		},
+		{
+			name: "spam.md",
+			contents: []string{
+				"---",
+				"tags:",
+				"- spam",
+				"- {eggs: true}",
+				"---",
+				"# Spam",
+			},
+		},
+	}, "markasten-input")
+
+	rootCmd := commands.NewRootCmd()
+	stderr := &bytes.Buffer{}
+	rootCmd.SetErr(stderr)
+	rootCmd.SetArgs([]string{
+		"tags",
+		"-i",
+		inputDir,
+		"-o",
+		filepath.Join(inputDir, "index.md"),
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	require.Equal(t, strings.Join([]string{
+		"bar.md:2:1: invalid frontmatter: did not find expected ',' or ']'",
+		"foo.md:3:3: tags is map[team:foo], which is not a list or string of tags",
+		"spam.md:4:3: tags contains map[eggs:true], which is not a valid tag",
+		"",
+	}, "\n"), strings.ReplaceAll(stderr.String(), inputDir+string(filepath.Separator), ""))
+
+	actualOutputBytes, err := os.ReadFile(filepath.Join(inputDir, "index.md"))
+	require.NoError(t, err)
+	require.Equal(t, "# Index\n## spam\n- [Spam](spam.md)", string(actualOutputBytes))
+}
+
+func TestTagsInject(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		additionalArgs []string
+		readme         []string
+		expected       []string
+		expectedError  string
+	}{
+		{
+			name: "tags inject between markers",
+			readme: []string{
+				"# Docs",
+				"An introduction to the docs.",
+				"",
+				"<!-- markasten:tags:start -->",
+				"An old index.",
+				"<!-- markasten:tags:end -->",
+				"",
+				"A footer.",
+				"",
+			},
+			expected: []string{
+				"# Docs",
+				"An introduction to the docs.",
+				"",
+				"<!-- markasten:tags:start -->",
+				"## foo",
+				"- [Foo](foo.md)",
+				"<!-- markasten:tags:end -->",
+				"",
+				"A footer.",
+				"",
+			},
+		},
+		{
+			name: "tags inject without markers",
+			readme: []string{
+				"# Docs",
+				"An introduction to the docs.",
+				"",
+			},
+			expected: []string{
+				"# Docs",
+				"An introduction to the docs.",
+				"",
+				"<!-- markasten:tags:start -->",
+				"## foo",
+				"- [Foo](foo.md)",
+				"<!-- markasten:tags:end -->",
+				"",
+			},
+		},
+		{
+			name:           "tags inject with a table of contents",
+			additionalArgs: []string{"--toc"},
+			readme: []string{
+				"# Docs",
+				"<!-- markasten:tags:start -->",
+				"<!-- markasten:tags:end -->",
+				"",
+			},
+			expected: []string{
+				"# Docs",
+				"<!-- markasten:tags:start -->",
+				"",
+				"---",
+				"",
+				"## Table of contents",
+				"- [foo](#foo)",
+				"",
+				"---",
+				"",
+				"## foo",
+				"- [Foo](foo.md)",
+				"<!-- markasten:tags:end -->",
+				"",
+			},
+		},
+		{
+			name: "tags inject with a missing end marker",
+			readme: []string{
+				"# Docs",
+				"<!-- markasten:tags:start -->",
+				"",
+			},
+			expectedError: `README.md: found "<!-- markasten:tags:start -->" without a following "<!-- markasten:tags:end -->"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputDir := writeFiles(t, []file{
+				{
+					name: "foo.md",
+					contents: []string{
+						"---",
+						"tags: [foo]",
+						"---",
+						"# Foo",
+					},
+				},
+				{
+					name:     "README.md",
+					contents: tc.readme,
+				},
+			}, "markasten-input")
+			readmePath := filepath.Join(inputDir, "README.md")
+
+			// Injecting the index again should leave the file unchanged.
+			for i := 0; i < 2; i++ {
+				rootCmd := commands.NewRootCmd()
+				rootCmd.SetArgs(append([]string{
+					"tags",
+					"-i",
+					inputDir,
+					"-o",
+					readmePath,
+					"--inject",
+				}, tc.additionalArgs...))
+				err := rootCmd.Execute()
+				if tc.expectedError != "" {
+					require.EqualError(t, err, filepath.Join(inputDir, tc.expectedError))
+					actualOutputBytes, err := os.ReadFile(readmePath)
+					require.NoError(t, err)
+					require.Equal(t, strings.Join(tc.readme, "\n"), string(actualOutputBytes))
+					return
+				}
+				require.NoError(t, err)
+			}
+
+			actualOutputBytes, err := os.ReadFile(readmePath)
+			require.NoError(t, err)
+			require.Equal(t, strings.Join(tc.expected, "\n"), string(actualOutputBytes))
+		})
+	}
+}
+
+func TestTagsCheck(t *testing.T) {
+	inputDir := writeFiles(t, []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags: [foo, bar]",
+				"---",
+				"# Foo",
+			},
+		},
+		{
+			name: "index.md",
+			contents: []string{
+				"# Index",
+				"## foo",
+				"- [Foo](foo.md)",
+			},
+		},
+	}, "markasten-input")
+	indexPath := filepath.Join(inputDir, "index.md")
+	check := func() (string, error) {
+		rootCmd := commands.NewRootCmd()
+		stdout := &bytes.Buffer{}
+		rootCmd.SetOut(stdout)
+		rootCmd.SetErr(&bytes.Buffer{})
+		rootCmd.SetArgs([]string{
+			"tags",
+			"-i",
+			inputDir,
+			"-o",
+			indexPath,
+			"--check",
+		})
+		err := rootCmd.Execute()
+		return strings.ReplaceAll(stdout.String(), inputDir+string(filepath.Separator), ""), err
+	}
+
+	diff, err := check()
+	require.EqualError(t, err, indexPath+" is out of date")
+	require.Equal(t, strings.Join([]string{
+		"--- index.md",
+		"+++ index.md (generated)",
+		"@@ -1,3 +1,6 @@",
+		" # Index",
+		"+## bar",
+		"+- [Foo](foo.md)",
+		"+",
+		" ## foo",
+		" - [Foo](foo.md)",
+		"",
+	}, "\n"), diff)
+	actualOutputBytes, err := os.ReadFile(indexPath)
+	require.NoError(t, err)
+	require.Equal(t, "# Index\n## foo\n- [Foo](foo.md)", string(actualOutputBytes))
+
+	rootCmd := commands.NewRootCmd()
+	rootCmd.SetArgs([]string{"tags", "-i", inputDir, "-o", indexPath})
+	require.NoError(t, rootCmd.Execute())
+
+	diff, err = check()
+	require.NoError(t, err)
+	require.Empty(t, diff)
+}
+
+func TestTagsWithTemplate(t *testing.T) {
+	inputDir := writeFiles(t, []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags: [foo, spam]",
+				"---",
+				"# Foo",
+			},
+		},
+		{
+			name: "team/bar.md",
+			contents: []string{
+				"---",
+				"tags: [spam]",
+				"---",
+				"# Bar",
+			},
+		},
+		{
+			name: "index.tmpl",
+			contents: []string{
+				"# {{ .Title }}",
+				"| Tag | Files |",
+				"| --- | --- |",
+				"{{ range .Tags }}| [{{ .Header }}](#{{ .Anchor }}) | {{ .Count }} |",
+				"{{ end }}",
+				"{{- range .Tags }}",
+				"<details><summary>{{ .Header }}</summary>",
+				"{{ range .Files }}",
+				"- [{{ .Title }}]({{ .Path }}){{ range .OtherTags }} #{{ . }}{{ end }}",
+				"{{- end }}",
+				"</details>",
+				"{{ end }}",
+			},
+		},
+	}, "markasten-input")
+	outputPath := filepath.Join(inputDir, "index.md")
+
+	rootCmd := commands.NewRootCmd()
+	rootCmd.SetArgs([]string{
+		"tags",
+		"-i",
+		inputDir,
+		"-o",
+		outputPath,
+		"--capitalize",
+		"--template",
+		filepath.Join(inputDir, "index.tmpl"),
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	actualOutputBytes, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	require.Equal(t, strings.Join([]string{
+		"# Index",
+		"| Tag | Files |",
+		"| --- | --- |",
+		"| [Foo](#Foo) | 1 |",
+		"| [Spam](#Spam) | 2 |",
+		"",
+		"<details><summary>Foo</summary>",
+		"",
+		"- [Foo](foo.md) #spam",
+		"</details>",
+		"",
+		"<details><summary>Spam</summary>",
+		"",
+		"- [Foo](foo.md) #foo",
+		"- [Bar](team/bar.md)",
+		"</details>",
+		"",
+	}, "\n"), string(actualOutputBytes))
+}
+
+func TestTagsSplit(t *testing.T) {
+	inputDir := writeFiles(t, []file{
+		{
+			name: "notes/foo.md",
+			contents: []string{
+				"---",
+				"tags: [team/backend, readme]",
+				"---",
+				"# Foo",
+			},
+		},
+		{
+			name: "notes/bar.md",
+			contents: []string{
+				"---",
+				"tags: [team, C++ & Go]",
+				"---",
+				"# Bar",
+			},
+		},
+	}, "markasten-input")
+	outputDir := filepath.Join(inputDir, "tags")
+
+	// Splitting the index again should leave the pages unchanged.
+	for i := 0; i < 2; i++ {
+		rootCmd := commands.NewRootCmd()
+		rootCmd.SetArgs([]string{
+			"tags",
+			"-i",
+			inputDir,
+			"-o",
+			outputDir,
+			"--split",
+			"--nested-tags",
+			"--tag-links",
+		})
+		require.NoError(t, rootCmd.Execute())
+	}
+
+	for _, outputFile := range []file{
+		{
+			name: "README.md",
+			contents: []string{
+				"# Index",
+				"- [C++ & Go](c-go.md) (1 note)",
+				"- [readme](readme-1.md) (1 note)",
+				"- [team](team.md) (2 notes)",
+				"  - [backend](team-backend.md) (1 note)",
+				"",
+			},
+		},
+		{
+			name: "c-go.md",
+			contents: []string{
+				"# C++ & Go",
+				"- [Bar](../notes/bar.md) `team`",
+				"",
+			},
+		},
+		{
+			name: "readme-1.md",
+			contents: []string{
+				"# readme",
+				"- [Foo](../notes/foo.md) `team/backend`",
+				"",
+			},
+		},
+		{
+			name: "team.md",
+			contents: []string{
+				"# team",
+				"- [Bar](../notes/bar.md) `C++ & Go`",
+				"- [Foo](../notes/foo.md) `readme`",
+				"",
+			},
+		},
+		{
+			name: "team-backend.md",
+			contents: []string{
+				"# backend",
+				"- [Foo](../notes/foo.md) `readme`",
+				"",
+			},
+		},
+	} {
+		actualOutputBytes, err := os.ReadFile(filepath.Join(outputDir, outputFile.name))
+		require.NoError(t, err)
+		require.Equal(t, strings.Join(outputFile.contents, "\n"), string(actualOutputBytes))
+	}
+}
+
+func TestTagsSplitWithCollidingSlugs(t *testing.T) {
+	inputDir := writeFiles(t, []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags: [a b, a-b, a-b-1]",
+				"---",
+				"# Foo",
+			},
+		},
+	}, "markasten-input")
+	outputDir := filepath.Join(inputDir, "tags")
+
+	rootCmd := commands.NewRootCmd()
+	rootCmd.SetArgs([]string{
+		"tags",
+		"-i",
+		inputDir,
+		"-o",
+		outputDir,
+		"--split",
+		"--wiki-links",
+	})
+	require.NoError(t, rootCmd.Execute())
+
+	for _, outputFile := range []file{
+		{
+			name: "README.md",
+			contents: []string{
+				"# Index",
+				"- [a b](a-b) (1 note)",
+				"- [a-b](a-b-2) (1 note)",
+				"- [a-b-1](a-b-1) (1 note)",
+				"",
+			},
+		},
+		{
+			name: "a-b.md",
+			contents: []string{
+				"# a b",
+				"- [Foo](../foo)",
+				"",
+			},
+		},
+		{
+			name: "a-b-1.md",
+			contents: []string{
+				"# a-b-1",
+				"- [Foo](../foo)",
+				"",
+			},
+		},
+		{
+			name: "a-b-2.md",
+			contents: []string{
+				"# a-b",
+				"- [Foo](../foo)",
+				"",
+			},
+		},
+	} {
+		actualOutputBytes, err := os.ReadFile(filepath.Join(outputDir, outputFile.name))
+		require.NoError(t, err)
+		require.Equal(t, strings.Join(outputFile.contents, "\n"), string(actualOutputBytes))
+	}
+}
+
+func TestTagsWithEmptyTagSeparator(t *testing.T) {
+	inputDir := writeFiles(t, []file{
+		{
+			name: "foo.md",
+			contents: []string{
+				"---",
+				"tags: [team/foo]",
+				"---",
+				"# Foo",
+			},
+		},
+	}, "markasten-input")
+
+	rootCmd := commands.NewRootCmd()
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{
+		"tags",
+		"-i",
+		inputDir,
+		"-o",
+		filepath.Join(inputDir, "index.md"),
+		"--nested-tags",
+		"--tag-separator",
+		"",
+	})
+	require.EqualError(t, rootCmd.Execute(), "--tag-separator cannot be empty when using --nested-tags")
+
+	_, err := os.Stat(filepath.Join(inputDir, "index.md"))
+	require.True(t, os.IsNotExist(err))
+}
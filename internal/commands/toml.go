@@ -0,0 +1,411 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	tomlBareKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+	tomlDateRegexp    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?([Zz]|[+-]\d{2}:\d{2})?)?$|^\d{2}:\d{2}:\d{2}(\.\d+)?$`)
+)
+
+// ParseTOML parses a TOML document, such as the frontmatter of a Hugo page,
+// into the same types that YAML is unmarshalled into. Dates and times are
+// returned as strings.
+func ParseTOML(data string) (map[string]interface{}, error) {
+	p := &tomlParser{data: data, line: 1}
+	document := make(map[string]interface{})
+	table := document
+	for {
+		p.skipBlankLines()
+		if p.done() {
+			return document, nil
+		}
+		var err error
+		if p.peek() == '[' {
+			table, err = p.parseTableHeader(document)
+		} else {
+			err = p.parseKeyValue(table)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.done() && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, p.errorf("expected a new line, found %q", p.peek())
+		}
+	}
+}
+
+// tomlError is a problem parsing a TOML document, at a 1-based line and
+// column of the document.
+type tomlError struct {
+	line   int
+	column int
+	err    error
+}
+
+func (e *tomlError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.line, e.column, e.err)
+}
+
+// tomlParser is a minimal parser of TOML documents.
+type tomlParser struct {
+	data string
+	pos  int
+	line int
+	// lineStart is the offset of the start of the current line.
+	lineStart int
+}
+
+func (p *tomlParser) errorf(format string, v ...any) error {
+	return &tomlError{
+		line:   p.line,
+		column: p.pos - p.lineStart + 1,
+		err:    fmt.Errorf("toml: %s", fmt.Sprintf(format, v...)),
+	}
+}
+
+func (p *tomlParser) done() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) advance(n int) {
+	advanced := p.data[p.pos : p.pos+n]
+	if i := strings.LastIndex(advanced, "\n"); i >= 0 {
+		p.line += strings.Count(advanced, "\n")
+		p.lineStart = p.pos + i + 1
+	}
+	p.pos += n
+}
+
+// skipSpaces skips spaces, tabs and any comment up to the end of the line.
+func (p *tomlParser) skipSpaces() {
+	for !p.done() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.advance(1)
+	}
+	if !p.done() && p.peek() == '#' {
+		for !p.done() && p.peek() != '\n' {
+			p.advance(1)
+		}
+	}
+}
+
+// skipBlankLines skips whitespace, comments and new lines.
+func (p *tomlParser) skipBlankLines() {
+	for {
+		p.skipSpaces()
+		if p.done() || (p.peek() != '\n' && p.peek() != '\r') {
+			return
+		}
+		p.advance(1)
+	}
+}
+
+// parseTableHeader parses a [table] or [[array.of.tables]] header, and
+// returns the table which following keys belong to.
+func (p *tomlParser) parseTableHeader(document map[string]interface{}) (map[string]interface{}, error) {
+	arrayOfTables := strings.HasPrefix(p.data[p.pos:], "[[")
+	if arrayOfTables {
+		p.advance(2)
+	} else {
+		p.advance(1)
+	}
+	key, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if arrayOfTables {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.data[p.pos:], closing) {
+		return nil, p.errorf("expected %s after table name", closing)
+	}
+	p.advance(len(closing))
+
+	parent, err := p.table(document, key[:len(key)-1])
+	if err != nil {
+		return nil, err
+	}
+	name := key[len(key)-1]
+	if arrayOfTables {
+		tables, _ := parent[name].([]interface{})
+		if _, exists := parent[name]; exists && tables == nil {
+			return nil, p.errorf("%s is not an array of tables", strings.Join(key, "."))
+		}
+		table := make(map[string]interface{})
+		parent[name] = append(tables, table)
+		return table, nil
+	}
+	return p.table(parent, []string{name})
+}
+
+// table returns the table at the given path from parent, creating any
+// tables which do not exist. For arrays of tables, the last table is used.
+func (p *tomlParser) table(parent map[string]interface{}, path []string) (map[string]interface{}, error) {
+	table := parent
+	for _, name := range path {
+		switch value := table[name].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[name] = child
+			table = child
+		case map[string]interface{}:
+			table = value
+		case []interface{}:
+			if len(value) == 0 {
+				return nil, p.errorf("%s is not a table", name)
+			}
+			last, ok := value[len(value)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("%s is not a table", name)
+			}
+			table = last
+		default:
+			return nil, p.errorf("%s is not a table", name)
+		}
+	}
+	return table, nil
+}
+
+// parseKeyValue parses a key = value pair into the given table.
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.done() || p.peek() != '=' {
+		return p.errorf("expected = after %s", strings.Join(key, "."))
+	}
+	p.advance(1)
+	p.skipSpaces()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	parent, err := p.table(table, key[:len(key)-1])
+	if err != nil {
+		return err
+	}
+	name := key[len(key)-1]
+	if _, exists := parent[name]; exists {
+		return p.errorf("%s is defined more than once", strings.Join(key, "."))
+	}
+	parent[name] = value
+	return nil
+}
+
+// parseKey parses a bare, quoted or dotted key.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var key []string
+	for {
+		p.skipSpaces()
+		if p.done() {
+			return nil, p.errorf("expected a key")
+		}
+		switch p.peek() {
+		case '"', '\'':
+			part, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			key = append(key, part)
+		default:
+			part := tomlBareKeyRegexp.FindString(p.data[p.pos:])
+			if part == "" {
+				return nil, p.errorf("expected a key, found %q", p.peek())
+			}
+			p.advance(len(part))
+			key = append(key, part)
+		}
+		p.skipSpaces()
+		if p.done() || p.peek() != '.' {
+			return key, nil
+		}
+		p.advance(1)
+	}
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.done() {
+		return nil, p.errorf("expected a value")
+	}
+	switch p.peek() {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+	end := p.pos
+	for end < len(p.data) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.data[end])) {
+		end++
+	}
+	// Dates and times can contain a space between the date and time.
+	if end+9 <= len(p.data) && p.data[end] == ' ' && tomlDateRegexp.MatchString(p.data[p.pos:end]+"T"+p.data[end+1:end+9]) {
+		end += 9
+		for end < len(p.data) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.data[end])) {
+			end++
+		}
+	}
+	token := p.data[p.pos:end]
+	p.advance(len(token))
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	number := strings.ReplaceAll(token, "_", "")
+	if i, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return int(i), nil
+	}
+	if f, err := strconv.ParseFloat(strings.TrimPrefix(number, "+"), 64); err == nil {
+		return f, nil
+	}
+	if tomlDateRegexp.MatchString(token) {
+		return token, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// parseString parses a basic or literal string, either of which can be
+// multi-line.
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.data[p.pos : p.pos+1]
+	multiLine := strings.HasPrefix(p.data[p.pos:], strings.Repeat(quote, 3))
+	if multiLine {
+		quote = strings.Repeat(quote, 3)
+	}
+	p.advance(len(quote))
+	if multiLine && strings.HasPrefix(p.data[p.pos:], "\n") {
+		// A new line immediately after the opening quotes is trimmed.
+		p.advance(1)
+	}
+	var value strings.Builder
+	for {
+		if p.done() || (!multiLine && p.peek() == '\n') {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.data[p.pos:], quote) {
+			p.advance(len(quote))
+			return value.String(), nil
+		}
+		c := p.peek()
+		if c != '\\' || quote[0] == '\'' {
+			value.WriteByte(c)
+			p.advance(1)
+			continue
+		}
+		if p.pos+1 >= len(p.data) {
+			return "", p.errorf("unterminated string")
+		}
+		escape := p.data[p.pos+1]
+		p.advance(2)
+		switch escape {
+		case 'b':
+			value.WriteByte('\b')
+		case 't':
+			value.WriteByte('\t')
+		case 'n':
+			value.WriteByte('\n')
+		case 'f':
+			value.WriteByte('\f')
+		case 'r':
+			value.WriteByte('\r')
+		case '"', '\\':
+			value.WriteByte(escape)
+		case 'u', 'U':
+			length := 4
+			if escape == 'U' {
+				length = 8
+			}
+			if p.pos+length > len(p.data) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(p.data[p.pos:p.pos+length], 16, 32)
+			if err != nil {
+				return "", p.errorf("invalid unicode escape")
+			}
+			value.WriteRune(rune(code))
+			p.advance(length)
+		case '\n', ' ', '\t', '\r':
+			if !multiLine {
+				return "", p.errorf("invalid escape")
+			}
+			// A backslash at the end of a line trims the following
+			// whitespace.
+			for !p.done() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+				p.advance(1)
+			}
+		default:
+			return "", p.errorf("invalid escape \\%c", escape)
+		}
+	}
+}
+
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.advance(1)
+	array := []interface{}{}
+	for {
+		p.skipBlankLines()
+		if p.done() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.advance(1)
+			return array, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+		p.skipBlankLines()
+		if p.done() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ',' {
+			p.advance(1)
+		} else if p.peek() != ']' {
+			return nil, p.errorf("expected , or ] in array, found %q", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	p.advance(1)
+	table := make(map[string]interface{})
+	p.skipSpaces()
+	if !p.done() && p.peek() == '}' {
+		p.advance(1)
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.done() {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.peek() {
+		case '}':
+			p.advance(1)
+			return table, nil
+		case ',':
+			p.advance(1)
+		default:
+			return nil, p.errorf("expected , or } in inline table, found %q", p.peek())
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package commands_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andykuszyk/markasten/internal/commands"
+
+	"github.com/stretchr/testify/require"
+)
+
+type parseTOMLTestCase struct {
+	name   string
+	input  []string
+	values map[string]interface{}
+	err    string
+}
+
+func TestParseTOML(t *testing.T) {
+	for _, tc := range []parseTOMLTestCase{
+		{
+			name:   "empty document",
+			input:  []string{""},
+			values: map[string]interface{}{},
+		},
+		{
+			name: "basic and literal strings",
+			input: []string{
+				`title = "Foo"`,
+				`path = 'C:\foo'`,
+			},
+			values: map[string]interface{}{"title": "Foo", "path": `C:\foo`},
+		},
+		{
+			name: "escapes",
+			input: []string{
+				`quote = "say \"foo\""`,
+				`whitespace = "a\tb\nc"`,
+				`unicode = "\u00e9\U0001F600"`,
+				`backslash = "a\\b"`,
+			},
+			values: map[string]interface{}{
+				"quote":      `say "foo"`,
+				"whitespace": "a\tb\nc",
+				"unicode":    "é😀",
+				"backslash":  `a\b`,
+			},
+		},
+		{
+			name: "multi-line strings",
+			input: []string{
+				`basic = """`,
+				`foo`,
+				`bar"""`,
+				`trimmed = """foo \`,
+				`    bar"""`,
+				`literal = '''`,
+				`foo\n'''`,
+			},
+			values: map[string]interface{}{
+				"basic":   "foo\nbar",
+				"trimmed": "foo bar",
+				"literal": "foo\\n",
+			},
+		},
+		{
+			name: "numbers, booleans and dates",
+			input: []string{
+				`count = 1_000`,
+				`hex = 0xff`,
+				`ratio = 0.5`,
+				`draft = true`,
+				`date = 2023-01-02T03:04:05Z`,
+				`spaced = 2023-01-02 03:04:05`,
+				`day = 2023-01-02 # a comment`,
+			},
+			values: map[string]interface{}{
+				"count":  1000,
+				"hex":    255,
+				"ratio":  0.5,
+				"draft":  true,
+				"date":   "2023-01-02T03:04:05Z",
+				"spaced": "2023-01-02 03:04:05",
+				"day":    "2023-01-02",
+			},
+		},
+		{
+			name: "arrays",
+			input: []string{
+				`tags = ["foo", 'bar', 1]`,
+				`nested = [`,
+				`  ["spam"],`,
+				`  [],`,
+				`]`,
+			},
+			values: map[string]interface{}{
+				"tags":   []interface{}{"foo", "bar", 1},
+				"nested": []interface{}{[]interface{}{"spam"}, []interface{}{}},
+			},
+		},
+		{
+			name: "dotted and quoted keys",
+			input: []string{
+				`site.title = "Foo"`,
+				`site."base url" = "/"`,
+				`"key.with.dots" = 1`,
+			},
+			values: map[string]interface{}{
+				"site":          map[string]interface{}{"title": "Foo", "base url": "/"},
+				"key.with.dots": 1,
+			},
+		},
+		{
+			name: "tables and inline tables",
+			input: []string{
+				`title = "Foo"`,
+				`[params]`,
+				`author = { name = "Bar", email = "bar@example.com" }`,
+				`[params.extra]`,
+				`tags = ["spam"]`,
+			},
+			values: map[string]interface{}{
+				"title": "Foo",
+				"params": map[string]interface{}{
+					"author": map[string]interface{}{"name": "Bar", "email": "bar@example.com"},
+					"extra":  map[string]interface{}{"tags": []interface{}{"spam"}},
+				},
+			},
+		},
+		{
+			name: "arrays of tables",
+			input: []string{
+				`[[authors]]`,
+				`name = "Foo"`,
+				`[authors.contact]`,
+				`email = "foo@example.com"`,
+				`[[authors]]`,
+				`name = "Bar"`,
+			},
+			values: map[string]interface{}{
+				"authors": []interface{}{
+					map[string]interface{}{
+						"name":    "Foo",
+						"contact": map[string]interface{}{"email": "foo@example.com"},
+					},
+					map[string]interface{}{"name": "Bar"},
+				},
+			},
+		},
+		{
+			name:  "unterminated string",
+			input: []string{`title = "Foo`},
+			err:   "line 1, column 13: toml: unterminated string",
+		},
+		{
+			name: "invalid escape",
+			input: []string{
+				`title = "Foo"`,
+				`path = "C:\qux"`,
+			},
+			err: `line 2, column 13: toml: invalid escape \q`,
+		},
+		{
+			name: "missing equals",
+			input: []string{
+				`title = "Foo"`,
+				`  tags ["foo"]`,
+			},
+			err: "line 2, column 8: toml: expected = after tags",
+		},
+		{
+			name:  "invalid value",
+			input: []string{`draft = yes`},
+			err:   `line 1, column 12: toml: invalid value "yes"`,
+		},
+		{
+			name: "key defined more than once",
+			input: []string{
+				`title = "Foo"`,
+				`title = "Bar"`,
+			},
+			err: "line 2, column 14: toml: title is defined more than once",
+		},
+		{
+			name: "empty array used as a table",
+			input: []string{
+				`tags = []`,
+				`[tags.extra]`,
+			},
+			err: "line 2, column 13: toml: tags is not a table",
+		},
+		{
+			name: "array of values used as a table",
+			input: []string{
+				`tags = ["foo"]`,
+				`[tags.extra]`,
+			},
+			err: "line 2, column 13: toml: tags is not a table",
+		},
+		{
+			name: "table used as an array of tables",
+			input: []string{
+				`[authors]`,
+				`[[authors]]`,
+			},
+			err: "line 2, column 12: toml: authors is not an array of tables",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := commands.ParseTOML(strings.Join(tc.input, "\n"))
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
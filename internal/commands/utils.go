@@ -1,22 +1,23 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
 )
 
 var (
 	debugEnabled *bool
 )
 
-type frontmatter struct {
-	Tags []string
-}
-
 func debug(format string, v ...any) {
 	if *debugEnabled {
 		log.Printf(format, v...)
@@ -31,31 +32,25 @@ type indexedFile struct {
 	otherTags []string
 }
 
-func writeOrPanic(file *os.File, text string) {
-	_, err := io.WriteString(file, text)
+func writeOrPanic(w io.Writer, text string) {
+	_, err := io.WriteString(w, text)
 	if err != nil {
 		panic(err)
 	}
 }
 
+// relativeTo returns the path of filePath relative to the directory
+// containing relativeToPath. Both paths are made absolute first, so that
+// relative and absolute paths can be mixed.
 func relativeTo(filePath string, relativeToPath string) string {
-	dir := filepath.Dir(relativeToPath)
-	relative, err := filepath.Rel(dir, filePath)
+	dir := absPath(filepath.Dir(relativeToPath))
+	relative, err := filepath.Rel(dir, absPath(filePath))
 	if err != nil {
 		return filePath
 	}
 	return relative
 }
 
-func firstNonEmptyLine(lines []string) string {
-	for _, line := range lines {
-		if len(line) > 0 {
-			return line
-		}
-	}
-	return ""
-}
-
 type fullDirEntry struct {
 	dirEntry   fs.DirEntry
 	parentPath string
@@ -116,6 +111,22 @@ func searchForMarkdownFiles(dirEntries []fullDirEntry, root string) ([]fullDirEn
 	return entries, nil
 }
 
+// excludeFile returns the entries other than the file at the given path, so
+// that a command's own output is not scanned as a note when it is written
+// into the input directory.
+func excludeFile(dirEntries []fullDirEntry, path string) []fullDirEntry {
+	if path == "" {
+		return dirEntries
+	}
+	var entries []fullDirEntry
+	for _, dirEntry := range dirEntries {
+		if absPath(dirEntry.Name()) != absPath(path) {
+			entries = append(entries, dirEntry)
+		}
+	}
+	return entries
+}
+
 func countTitles(files []indexedFile) map[string]int {
 	titleCounts := make(map[string]int)
 	for _, file := range files {
@@ -125,6 +136,14 @@ func countTitles(files []indexedFile) map[string]int {
 
 }
 
+func isMarkdownFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
 func makeWikiLink(path string) string {
 	return path[:len(path)-len(filepath.Ext(path))]
 }
@@ -135,3 +154,130 @@ func headerToLink(header string) string {
 	// and a heading of "foo bar" requires a link of "foo-bar".
 	return strings.ReplaceAll(strings.ReplaceAll(header, ":", ""), " ", "-")
 }
+
+// repoRoot returns the top level of the git repository containing path,
+// which root-absolute links are resolved against, or path itself if it is
+// not in a repository.
+func repoRoot(path string) string {
+	dir := absPath(path)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return absPath(path)
+		}
+		dir = parent
+	}
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// linkRoot returns the directory root-absolute links are resolved against,
+// which is root if it is set, or otherwise the top level of the git
+// repository containing inputPath.
+func linkRoot(root string, inputPath string) string {
+	if root != "" {
+		return root
+	}
+	return repoRoot(inputPath)
+}
+
+// rootFlagUsage is the usage of the --root flag of commands which resolve
+// root-absolute links.
+const rootFlagUsage = "The directory root-absolute links such as /path/to/note.md are relative to, which defaults to the top level of the git repository containing the input files, or the input directory if it is not in one"
+
+// checkFlagUsage is the usage of the --check flag of commands which generate
+// output files.
+const checkFlagUsage = "If set, the output will not be written, and instead the command will fail with a diff if the existing output is out of date"
+
+// writeOutput writes generated contents to the file at path, or to stdout if
+// path is empty. If check is true, the file is not written, and instead a
+// unified diff between the file and contents is printed and an error is
+// returned if they differ.
+func writeOutput(cmd *cobra.Command, path string, contents string, check bool) error {
+	if path == "" {
+		if check {
+			return errors.New("--check requires an output file to be set using -o")
+		}
+		writeOrPanic(cmd.OutOrStdout(), contents)
+		return nil
+	}
+	if check {
+		return checkOutput(cmd, path, contents)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// checkOutput prints a unified diff between the file at path and its
+// generated contents, and returns an error if they differ.
+func checkOutput(cmd *cobra.Command, path string, contents string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		panic(err)
+	}
+	if string(existing) == contents {
+		return nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(contents),
+		FromFile: path,
+		ToFile:   path + " (generated)",
+		Context:  3,
+	})
+	if err != nil {
+		panic(err)
+	}
+	writeOrPanic(cmd.OutOrStdout(), diff)
+	return fmt.Errorf("%s is out of date", path)
+}
+
+// replaceMarkedSection replaces the section enclosed by the start and end
+// markers (inclusive) with the given section. If the markers are not found,
+// the section is appended to the end of the contents.
+func replaceMarkedSection(contents string, startMarker string, endMarker string, section string) (string, error) {
+	start := strings.Index(contents, startMarker)
+	end := strings.Index(contents, endMarker)
+	if start < 0 && end < 0 {
+		trimmed := strings.TrimRight(contents, "\n")
+		if trimmed == "" {
+			return section + "\n", nil
+		}
+		return trimmed + "\n\n" + section + "\n", nil
+	}
+	if end < 0 {
+		return "", fmt.Errorf("found %q without a following %q", startMarker, endMarker)
+	}
+	if start < 0 || end < start {
+		return "", fmt.Errorf("found %q without a preceding %q", endMarker, startMarker)
+	}
+	return contents[:start] + section + contents[end+len(endMarker):], nil
+}
+
+// stripMarkedSection removes the section enclosed by the start and end
+// markers (inclusive), along with the blank lines separating it from the
+// preceding contents.
+func stripMarkedSection(contents string, startMarker string, endMarker string) string {
+	start := strings.Index(contents, startMarker)
+	end := strings.Index(contents, endMarker)
+	if start < 0 || end < start {
+		return contents
+	}
+	before := strings.TrimRight(contents[:start], "\n")
+	after := strings.TrimLeft(contents[end+len(endMarker):], "\n")
+	if after == "" {
+		return before + "\n"
+	}
+	return before + "\n\n" + after
+}